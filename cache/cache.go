@@ -0,0 +1,247 @@
+// Package cache stores Maps API responses within Google's caching terms. Place IDs may be
+// kept indefinitely, but every other piece of content, latitude/longitude included, must be
+// refreshed within 30 days. Entries are JSON encoded so any Backend can hold any response type.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxTTL is the longest content other than place IDs may be cached for
+const MaxTTL = 30 * 24 * time.Hour
+
+// Status reports how a response was served
+type Status string
+
+const (
+	StatusHit   = Status("HIT")   // Fresh cached content
+	StatusStale = Status("STALE") // Expired content served while it is refreshed in the background
+	StatusMiss  = Status("MISS")  // Fetched from upstream
+)
+
+// Entry is a cached response
+type Entry struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value,omitempty"`    // Nil once content expired and only place IDs are kept
+	PlaceIDs  []string        `json:"placeIds,omitempty"` // Place IDs found in the value. Kept after it expires
+	StoredAt  time.Time       `json:"storedAt"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+// Backend stores entries by key. Implementations must be safe for concurrent use
+type Backend interface {
+	Get(key string) (Entry, bool)
+	Set(key string, e Entry) error
+	Delete(key string) error
+	Keys() ([]string, error)
+}
+
+// PlaceIDer is implemented by responses holding places, so their IDs outlive the cached content
+type PlaceIDer interface {
+	PlaceIDs() []string
+}
+
+// Cache serves responses from a Backend, fetching and storing them on a miss
+type Cache struct {
+	backend  Backend
+	ttl      time.Duration
+	staleFor time.Duration
+	now      func() time.Time
+
+	mu         sync.Mutex
+	refreshing map[string]bool // keys being revalidated in the background
+}
+
+type Option func(*Cache)
+
+// WithTTL sets how long content is fresh. It is capped at MaxTTL. Defaults to 24 hours
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = min(ttl, MaxTTL)
+	}
+}
+
+// WithStaleWhileRevalidate serves expired content for up to d while it is refreshed in the background.
+// Content is never served past MaxTTL after it was fetched
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(c *Cache) {
+		c.staleFor = d
+	}
+}
+
+// WithClock replaces time.Now. Used in tests
+func WithClock(now func() time.Time) Option {
+	return func(c *Cache) {
+		c.now = now
+	}
+}
+
+func New(backend Backend, opts ...Option) *Cache {
+	c := &Cache{backend: backend, ttl: 24 * time.Hour, now: time.Now, refreshing: map[string]bool{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Key builds a cache key from the kind of request and everything that shapes its response,
+// such as the request body and field masks. Eg: textsearch:3f2a...
+func Key(kind string, parts ...any) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, p := range parts {
+		enc.Encode(p)
+	}
+	return kind + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Fetch returns the cached value for key, calling fetch and storing its result when there is none.
+// Errors are never cached. A nil cache always calls fetch
+func Fetch[T any](ctx context.Context, c *Cache, key string, fetch func(context.Context) (T, error)) (T, Status, error) {
+	if c == nil {
+		v, err := fetch(ctx)
+		return v, StatusMiss, err
+	}
+	now := c.now()
+	if e, ok := c.backend.Get(key); ok && e.Value != nil {
+		var v T
+		fresh := now.Before(e.ExpiresAt)
+		if (fresh || now.Before(c.staleUntil(e))) && json.Unmarshal(e.Value, &v) == nil {
+			if fresh {
+				return v, StatusHit, nil
+			}
+			c.revalidate(key, func(ctx context.Context) (any, error) { return fetch(ctx) })
+			return v, StatusStale, nil
+		}
+		if !now.Before(c.staleUntil(e)) {
+			c.expire(e)
+		}
+	}
+	v, err := fetch(ctx)
+	if err != nil {
+		return v, StatusMiss, err
+	}
+	c.store(key, v)
+	return v, StatusMiss, nil
+}
+
+// Content may be served stale until the revalidation window closes or MaxTTL is reached
+func (c *Cache) staleUntil(e Entry) time.Time {
+	until := e.ExpiresAt.Add(c.staleFor)
+	if limit := e.StoredAt.Add(MaxTTL); limit.Before(until) {
+		return limit
+	}
+	return until
+}
+
+// Refresh key in the background unless a refresh is already running
+func (c *Cache) revalidate(key string, fetch func(context.Context) (any, error)) {
+	c.mu.Lock()
+	if c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.mu.Unlock()
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+		// The request that triggered the refresh has already been answered, so it gets its own context
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if v, err := fetch(ctx); err == nil {
+			c.store(key, v)
+		}
+	}()
+}
+
+func (c *Cache) store(key string, v any) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	now := c.now()
+	e := Entry{Key: key, Value: value, StoredAt: now, ExpiresAt: now.Add(c.ttl)}
+	if ider, ok := v.(PlaceIDer); ok {
+		e.PlaceIDs = ider.PlaceIDs()
+	}
+	c.backend.Set(key, e)
+}
+
+// Drop expired content, keeping the place IDs it held
+func (c *Cache) expire(e Entry) error {
+	if len(e.PlaceIDs) == 0 {
+		return c.backend.Delete(e.Key)
+	}
+	e.Value = nil
+	return c.backend.Set(e.Key, e)
+}
+
+// PlaceIDs returns the place IDs last seen for key, even once its content expired
+func (c *Cache) PlaceIDs(key string) []string {
+	e, _ := c.backend.Get(key)
+	return e.PlaceIDs
+}
+
+// Sweep drops content that can no longer be served, keeping place IDs. It returns the number of entries expired
+func (c *Cache) Sweep() (int, error) {
+	keys, err := c.backend.Keys()
+	if err != nil {
+		return 0, err
+	}
+	now := c.now()
+	expired := 0
+	for _, key := range keys {
+		e, ok := c.backend.Get(key)
+		if !ok || e.Value == nil || now.Before(c.staleUntil(e)) {
+			continue
+		}
+		if err := c.expire(e); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// SweepEvery runs Sweep every d until ctx is done
+func (c *Cache) SweepEvery(ctx context.Context, d time.Duration) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Sweep()
+		}
+	}
+}
+
+// Purge deletes every entry, place IDs included, whose key starts with prefix. An empty prefix empties the cache
+func (c *Cache) Purge(prefix string) (int, error) {
+	keys, err := c.backend.Keys()
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if err := c.backend.Delete(key); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
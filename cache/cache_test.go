@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type places struct {
+	IDs  []string `json:"ids"`
+	Name string   `json:"name"`
+}
+
+func (p places) PlaceIDs() []string { return p.IDs }
+
+// Clock the tests move by hand
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func counter(calls *int, v places) func(context.Context) (places, error) {
+	return func(context.Context) (places, error) {
+		*calls++
+		return v, nil
+	}
+}
+
+// Testing entries are served fresh, then stale while revalidating, then refetched. Place IDs outlive the content
+func Test_Fetch(t *testing.T) {
+	clk := &clock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := New(NewMemory(10), WithTTL(time.Hour), WithStaleWhileRevalidate(time.Hour), WithClock(clk.now))
+	ctx := context.Background()
+	calls := 0
+	key := Key("textsearch", "pizza")
+	v, status, err := Fetch(ctx, c, key, counter(&calls, places{IDs: []string{"a"}, Name: "first"}))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusMiss, status)
+	assert.Equal(t, "first", v.Name)
+
+	v, status, _ = Fetch(ctx, c, key, counter(&calls, places{Name: "second"}))
+	assert.Equal(t, StatusHit, status)
+	assert.Equal(t, "first", v.Name)
+	assert.Equal(t, 1, calls)
+
+	clk.t = clk.t.Add(90 * time.Minute) // expired, inside the revalidation window
+	v, status, _ = Fetch(ctx, c, key, counter(&calls, places{IDs: []string{"b"}, Name: "second"}))
+	assert.Equal(t, StatusStale, status)
+	assert.Equal(t, "first", v.Name)
+	assert.Eventually(t, func() bool { return len(c.PlaceIDs(key)) == 1 && c.PlaceIDs(key)[0] == "b" }, time.Second, time.Millisecond)
+
+	clk.t = clk.t.Add(3 * time.Hour) // past the window: content is dropped and fetched again
+	_, err = c.Sweep()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, c.PlaceIDs(key))
+	v, status, _ = Fetch(ctx, c, key, counter(&calls, places{Name: "third"}))
+	assert.Equal(t, StatusMiss, status)
+	assert.Equal(t, "third", v.Name)
+
+	_, status, err = Fetch(ctx, c, Key("textsearch", "fail"), func(context.Context) (places, error) { return places{}, errors.New("quota") })
+	assert.Error(t, err)
+	assert.Equal(t, StatusMiss, status)
+	keys, _ := c.backend.Keys()
+	assert.Len(t, keys, 1) // errors are not cached
+
+	v, _, _ = Fetch(ctx, nil, key, counter(&calls, places{Name: "uncached"}))
+	assert.Equal(t, "uncached", v.Name)
+}
+
+// Testing content is never served past 30 days, whatever the options
+func Test_Fetch_MaxTTL(t *testing.T) {
+	clk := &clock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := New(NewMemory(0), WithTTL(90*24*time.Hour), WithStaleWhileRevalidate(24*time.Hour), WithClock(clk.now))
+	assert.Equal(t, MaxTTL, c.ttl)
+	calls := 0
+	Fetch(context.Background(), c, "k", counter(&calls, places{}))
+	clk.t = clk.t.Add(MaxTTL + time.Minute)
+	_, status, _ := Fetch(context.Background(), c, "k", counter(&calls, places{}))
+	assert.Equal(t, StatusMiss, status)
+	assert.Equal(t, 2, calls)
+}
+
+// Testing the memory backend evicts the least recently used entry
+func Test_Memory_LRU(t *testing.T) {
+	m := NewMemory(2)
+	m.Set("a", Entry{Key: "a"})
+	m.Set("b", Entry{Key: "b"})
+	m.Get("a")
+	m.Set("c", Entry{Key: "c"})
+	_, ok := m.Get("b")
+	assert.False(t, ok)
+	_, ok = m.Get("a")
+	assert.True(t, ok)
+	keys, _ := m.Keys()
+	assert.ElementsMatch(t, []string{"a", "c"}, keys)
+}
+
+// Testing the disk backend persists entries across instances and purges by prefix
+func Test_Disk(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir)
+	assert.NoError(t, err)
+	c := New(d)
+	_, _, err = Fetch(context.Background(), c, Key("geocode", "Halifax"), func(context.Context) (places, error) { return places{Name: "Halifax"}, nil })
+	assert.NoError(t, err)
+	_, _, err = Fetch(context.Background(), c, Key("nearbysearch", 1), func(context.Context) (places, error) { return places{}, nil })
+	assert.NoError(t, err)
+
+	reopened, err := NewDisk(dir)
+	assert.NoError(t, err)
+	v, status, err := Fetch(context.Background(), New(reopened), Key("geocode", "Halifax"), func(context.Context) (places, error) { return places{}, errors.New("not called") })
+	assert.NoError(t, err)
+	assert.Equal(t, StatusHit, status)
+	assert.Equal(t, "Halifax", v.Name)
+
+	purged, err := c.Purge("geocode:")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	keys, err := reopened.Keys()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.NoError(t, d.Delete("missing"))
+}
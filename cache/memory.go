@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Memory is an in-memory Backend that evicts the least recently used entry once it holds capacity entries
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is the most recently used
+	items    map[string]*list.Element
+}
+
+// NewMemory returns a Memory backend holding at most capacity entries. A capacity of 0 or less is unbounded
+func NewMemory(capacity int) *Memory {
+	return &Memory{capacity: capacity, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (m *Memory) Get(key string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	m.order.MoveToFront(el)
+	return el.Value.(Entry), true
+}
+
+func (m *Memory) Set(key string, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		el.Value = e
+		m.order.MoveToFront(el)
+		return nil
+	}
+	m.items[key] = m.order.PushFront(e)
+	if m.capacity > 0 && m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(Entry).Key)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[key]; ok {
+		m.order.Remove(el)
+		delete(m.items, key)
+	}
+	return nil
+}
+
+func (m *Memory) Keys() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.items))
+	for key := range m.items {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -27,6 +27,9 @@ type ApiConfig struct {
 
 var defaultRequestsPerSecond = 10
 
+// APIs taking their parameters in a header, such as the New Places API, take the API key there too
+const apiKeyHeader = "X-Goog-Api-Key"
+
 // Client may be used to make requests to the Google Maps WebService APIs
 type Client struct {
 	httpClient        *http.Client
@@ -88,10 +91,7 @@ func (c *Client) get(ctx context.Context, config *ApiConfig, apiReq apiRequest,
 	if err := c.waitRateLimit(ctx); err != nil {
 		return nil, err
 	}
-	if c.baseURL == "" {
-		c.baseURL = config.Host + config.Path // get api base url concatenating host and path
-	}
-	req, err := http.NewRequest("GET", c.baseURL, nil)
+	req, err := http.NewRequest("GET", c.apiURL(config), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error: %s", err)
 	}
@@ -100,6 +100,7 @@ func (c *Client) get(ctx context.Context, config *ApiConfig, apiReq apiRequest,
 		for k, v := range headers {
 			req.Header.Set(k, v)
 		}
+		req.Header.Set(apiKeyHeader, c.apiKey)
 	}
 	if apiReq != nil {
 		q, err := c.setAuthQueryParam(apiReq.Params())
@@ -144,15 +145,11 @@ func (c *Client) post(ctx context.Context, config *ApiConfig, apiReq interface{}
 	if err := c.waitRateLimit(ctx); err != nil {
 		return nil, err
 	}
-	if c.baseURL == "" {
-		c.baseURL = config.Host + config.Path // get api base url concatenating host and path
-	}
-
 	body, err := json.Marshal(apiReq)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(body))
+	req, err := http.NewRequest("POST", c.apiURL(config), bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
@@ -160,10 +157,19 @@ func (c *Client) post(ctx context.Context, config *ApiConfig, apiReq interface{}
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	fmt.Printf("Post Request: %+v", req)
+	req.Header.Set(apiKeyHeader, c.apiKey)
 	return c.do(ctx, req)
 }
 
+// Get api url concatenating host and path. A custom base url replaces the host.
+// The client is shared across goroutines so it must not be modified here
+func (c *Client) apiURL(config *ApiConfig) string {
+	if c.baseURL != "" {
+		return c.baseURL + config.Path
+	}
+	return config.Host + config.Path
+}
+
 func (c *Client) setAuthQueryParam(q url.Values) (string, error) {
 	if c.apiKey != "" {
 		q.Set("key", c.apiKey)
@@ -186,7 +192,7 @@ func (c *Client) waitRateLimit(ctx context.Context) error {
 	return c.rateLimiter.Wait(ctx)
 }
 
-// WithBaseURL configures a Maps API client with a custom base url
+// WithBaseURL configures a Maps API client with a custom base url used in place of the API host
 func WithBaseURL(baseURL string) ClientConfig {
 	return func(c *Client) error {
 		c.baseURL = baseURL
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// Columns a CSV export may have, besides the names of Item.Properties
+var Columns = []string{"id", "name", "address", "latitude", "longitude", "primaryType", "types", "businessStatus", "phone", "rating", "userRatingCount", "plusCode"}
+
+// Columns of a CSV export that does not pick any
+var DefaultColumns = []string{"id", "name", "address", "latitude", "longitude", "primaryType", "rating"}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns}
+	if err := cw.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// ValidateColumns checks columns are all known. extra names properties the items will carry. Eg: distanceMeters
+func ValidateColumns(columns []string, extra ...string) error {
+	for _, c := range columns {
+		if !slices.Contains(Columns, c) && !slices.Contains(extra, c) {
+			return fmt.Errorf("export: unknown column %q", c)
+		}
+	}
+	return nil
+}
+
+func (cw *csvWriter) Write(item Item) error {
+	props := map[string]any{}
+	for _, prop := range placeProperties(item) {
+		props[prop.Name] = prop.Value
+	}
+	record := make([]string, len(cw.columns))
+	for i, c := range cw.columns {
+		switch {
+		case c == "id":
+			record[i] = item.Id
+		case c == "latitude" && hasLocation(item.Place):
+			record[i] = strconv.FormatFloat(item.Location.Latitude, 'f', -1, 64)
+		case c == "longitude" && hasLocation(item.Place):
+			record[i] = strconv.FormatFloat(item.Location.Longitude, 'f', -1, 64)
+		default:
+			record[i] = formatValue(props[c])
+		}
+	}
+	// Flushed per row so the response streams
+	if err := cw.w.Write(record); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
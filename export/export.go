@@ -0,0 +1,185 @@
+// Package export writes places as GeoJSON, CSV or KML. Writers stream one place at a time so
+// large result sets are never held as a single document in memory.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/geolocate/geo"
+)
+
+// Format of an export
+type Format string
+
+const (
+	FormatJSON    = Format("json") // The server's own JSON response. Not written by this package
+	FormatGeoJSON = Format("geojson")
+	FormatCSV     = Format("csv")
+	FormatKML     = Format("kml")
+)
+
+var ErrUnknownFormat = errors.New("export: unknown format, use json, geojson, csv or kml")
+
+// Media types of each format, preferred first
+var mediaTypes = map[Format][]string{
+	FormatJSON:    {"application/json"},
+	FormatGeoJSON: {"application/geo+json", "application/vnd.geo+json"},
+	FormatCSV:     {"text/csv"},
+	FormatKML:     {"application/vnd.google-earth.kml+xml"},
+}
+
+// ContentType is the Content-Type header of f
+func ContentType(f Format) string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml; charset=utf-8"
+	}
+	return mediaTypes[f][0]
+}
+
+// Negotiate picks the format from the format query param, then the Accept header by quality.
+// JSON is the default, including when Accept names nothing this package writes
+func Negotiate(accept, format string) (Format, error) {
+	if format != "" {
+		f := Format(strings.ToLower(format))
+		if _, ok := mediaTypes[f]; !ok {
+			return "", ErrUnknownFormat
+		}
+		return f, nil
+	}
+	type candidate struct {
+		format  Format
+		quality float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		for f, types := range mediaTypes {
+			for _, t := range types {
+				if t == mediaType && quality > 0 {
+					candidates = append(candidates, candidate{f, quality})
+				}
+			}
+		}
+	}
+	// Stable so equal qualities keep the client's order
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	if len(candidates) == 0 {
+		return FormatJSON, nil
+	}
+	return candidates[0].format, nil
+}
+
+// Property is a value computed for a place by the server. Eg: distanceMeters
+type Property struct {
+	Name  string
+	Value any // nil values are left out
+}
+
+// Item is a place to export, with the properties the server added to it
+type Item struct {
+	geo.Place
+	Properties []Property
+}
+
+// Writer streams items in a format. Close finishes the document; it does not close the underlying writer
+type Writer interface {
+	Write(item Item) error
+	Close() error
+}
+
+// Options shape an export
+type Options struct {
+	Columns []string // CSV columns, from Columns. Defaults to DefaultColumns
+	Name    string   // Document name, used by KML
+}
+
+// NewWriter starts an export to w
+func NewWriter(w io.Writer, f Format, opts Options) (Writer, error) {
+	switch f {
+	case FormatGeoJSON:
+		return newGeoJSONWriter(w), nil
+	case FormatCSV:
+		return newCSVWriter(w, opts.Columns)
+	case FormatKML:
+		return newKMLWriter(w, opts.Name)
+	}
+	return nil, fmt.Errorf("export: %q is not written by this package", f)
+}
+
+// Place fields exported by every format, by name. Empty values are left out
+func placeProperties(item Item) []Property {
+	p := item.Place
+	props := []Property{
+		{"name", nonEmpty(p.DisplayName.Text)},
+		{"address", nonEmpty(p.FormattedAddress)},
+		{"primaryType", nonEmpty(p.PrimaryType)},
+		{"types", nonEmpty(strings.Join(p.Types, ","))},
+		{"businessStatus", nonEmpty(string(p.BusinessStatus))},
+		{"phone", nonEmpty(p.PhoneNumber)},
+	}
+	if p.Rating > 0 {
+		props = append(props, Property{"rating", p.Rating})
+	}
+	if p.UserRatingCount > 0 {
+		props = append(props, Property{"userRatingCount", p.UserRatingCount})
+	}
+	if p.PlusCode != nil {
+		props = append(props, Property{"plusCode", nonEmpty(p.PlusCode.GlobalCode)})
+	}
+	props = append(props, item.Properties...)
+	kept := props[:0]
+	for _, prop := range props {
+		if f, ok := prop.Value.(*float64); prop.Value != nil && (!ok || f != nil) {
+			kept = append(kept, prop)
+		}
+	}
+	return kept
+}
+
+func nonEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Whether the place has a location. Google never places anything at exactly 0,0
+func hasLocation(p geo.Place) bool {
+	return p.Location != (geo.Location{})
+}
+
+// Property values as text, for CSV cells and KML data
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func testItems() []Item {
+	distance := 420.5
+	return []Item{
+		{Place: geo.Place{Id: "pizza", DisplayName: geo.LocalizedText{Text: "Pizza & Co"}, FormattedAddress: "1 Spring Garden Rd, Halifax, NS",
+			PrimaryType: "pizza_restaurant", Types: []string{"pizza_restaurant", "restaurant"}, Rating: 4.5, UserRatingCount: 120,
+			BusinessStatus: geo.BusinessStatusOperational, Location: geo.Location{Latitude: 44.645, Longitude: -63.573}},
+			Properties: []Property{{"distanceMeters", &distance}, {"openDuring", geo.OpenStatusOpen}}},
+		{Place: geo.Place{Id: "cafe", DisplayName: geo.LocalizedText{Text: `"Harbour" <Cafe>`}, PhoneNumber: "902 555 0100",
+			PlusCode: &geo.PlacePlusCode{GlobalCode: "87PRJ9V9+M5"}},
+			Properties: []Property{{"distanceMeters", (*float64)(nil)}}},
+	}
+}
+
+func golden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		assert.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+	want, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+}
+
+func export(t *testing.T, f Format, opts Options, items []Item) []byte {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, f, opts)
+	assert.NoError(t, err)
+	for _, item := range items {
+		assert.NoError(t, w.Write(item))
+	}
+	assert.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+// Testing each format against its golden file
+func Test_Writers(t *testing.T) {
+	golden(t, "places.geojson", export(t, FormatGeoJSON, Options{}, testItems()))
+	golden(t, "empty.geojson", export(t, FormatGeoJSON, Options{}, nil))
+	golden(t, "places.csv", export(t, FormatCSV, Options{}, testItems()))
+	golden(t, "columns.csv", export(t, FormatCSV, Options{Columns: []string{"name", "phone", "plusCode", "distanceMeters", "openDuring"}}, testItems()))
+	golden(t, "places.kml", export(t, FormatKML, Options{Name: "Pizza near me"}, testItems()))
+
+	_, err := NewWriter(&bytes.Buffer{}, FormatJSON, Options{})
+	assert.Error(t, err)
+	assert.NoError(t, ValidateColumns([]string{"id", "distanceMeters"}, "distanceMeters"))
+	assert.Error(t, ValidateColumns([]string{"id", "distance"}))
+}
+
+// Testing the format query param wins over Accept, which is ranked by quality
+func Test_Negotiate(t *testing.T) {
+	for _, tc := range []struct {
+		accept, format string
+		want           Format
+	}{
+		{"", "", FormatJSON},
+		{"text/csv", "KML", FormatKML},
+		{"application/geo+json", "", FormatGeoJSON},
+		{"text/csv;q=0.5, application/vnd.google-earth.kml+xml", "", FormatKML},
+		{"text/csv, application/json", "", FormatCSV},
+		{"text/html, */*;q=0.8", "", FormatJSON},
+		{"text/csv;q=0", "", FormatJSON},
+	} {
+		got, err := Negotiate(tc.accept, tc.format)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, tc)
+	}
+	_, err := Negotiate("", "xml")
+	assert.Equal(t, ErrUnknownFormat, err)
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// GeoJSON feature for a place. Places without a location have a null geometry
+type feature struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id,omitempty"`
+	Geometry   *point          `json:"geometry"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+type point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // longitude, latitude
+}
+
+// Writes a FeatureCollection one feature per line
+type geoJSONWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newGeoJSONWriter(w io.Writer) *geoJSONWriter {
+	return &geoJSONWriter{w: w}
+}
+
+func (g *geoJSONWriter) Write(item Item) error {
+	prefix := ",\n"
+	if g.count == 0 {
+		prefix = `{"type":"FeatureCollection","features":[` + "\n"
+	}
+	f := feature{Type: "Feature", ID: item.Id, Properties: orderedObject(placeProperties(item))}
+	if hasLocation(item.Place) {
+		f.Geometry = &point{Type: "Point", Coordinates: [2]float64{item.Location.Longitude, item.Location.Latitude}}
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	g.count++
+	_, err = io.WriteString(g.w, prefix+string(data))
+	return err
+}
+
+func (g *geoJSONWriter) Close() error {
+	if g.count == 0 {
+		_, err := io.WriteString(g.w, `{"type":"FeatureCollection","features":[]}`+"\n")
+		return err
+	}
+	_, err := io.WriteString(g.w, "\n]}\n")
+	return err
+}
+
+// JSON object of props in their order, which a map would lose
+func orderedObject(props []Property) json.RawMessage {
+	buf := []byte{'{'}
+	for i, prop := range props {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		name, _ := json.Marshal(prop.Name)
+		value, err := json.Marshal(prop.Value)
+		if err != nil {
+			value = []byte("null")
+		}
+		buf = append(append(append(buf, name...), ':'), value...)
+	}
+	return append(buf, '}')
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Writes a KML Document with a Placemark per place. Properties become ExtendedData
+type kmlWriter struct {
+	w io.Writer
+}
+
+func newKMLWriter(w io.Writer, name string) (*kmlWriter, error) {
+	if name == "" {
+		name = "Places"
+	}
+	_, err := fmt.Fprintf(w, "%s<kml xmlns=\"http://www.opengis.net/kml/2.2\">\n<Document>\n<name>%s</name>\n", xml.Header, escape(name))
+	return &kmlWriter{w: w}, err
+}
+
+func (k *kmlWriter) Write(item Item) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<Placemark id=\"%s\">\n", escape(item.Id))
+	props := placeProperties(item)
+	for _, prop := range props {
+		switch prop.Name {
+		case "name":
+			fmt.Fprintf(&b, "  <name>%s</name>\n", escape(formatValue(prop.Value)))
+		case "address":
+			fmt.Fprintf(&b, "  <address>%s</address>\n", escape(formatValue(prop.Value)))
+		}
+	}
+	b.WriteString("  <ExtendedData>\n")
+	for _, prop := range props {
+		if prop.Name != "name" && prop.Name != "address" {
+			fmt.Fprintf(&b, "    <Data name=\"%s\"><value>%s</value></Data>\n", escape(prop.Name), escape(formatValue(prop.Value)))
+		}
+	}
+	b.WriteString("  </ExtendedData>\n")
+	if hasLocation(item.Place) {
+		// KML coordinates are longitude,latitude
+		fmt.Fprintf(&b, "  <Point><coordinates>%s,%s</coordinates></Point>\n",
+			strconv.FormatFloat(item.Location.Longitude, 'f', -1, 64), strconv.FormatFloat(item.Location.Latitude, 'f', -1, 64))
+	}
+	b.WriteString("</Placemark>\n")
+	_, err := k.w.Write(b.Bytes())
+	return err
+}
+
+func (k *kmlWriter) Close() error {
+	_, err := io.WriteString(k.w, "</Document>\n</kml>\n")
+	return err
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
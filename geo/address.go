@@ -0,0 +1,176 @@
+package geo
+
+import (
+	"slices"
+	"strings"
+)
+
+// Address is a postal address assembled from the components of a geocoding result
+type Address struct {
+	StreetNumber     string `json:"streetNumber,omitempty"`
+	Route            string `json:"route,omitempty"`
+	Sublocality      string `json:"sublocality,omitempty"`
+	Locality         string `json:"locality,omitempty"` // City or town. Falls back to the postal town where there is no locality (UK)
+	AdminArea1       string `json:"adminArea1,omitempty"`
+	AdminArea1Code   string `json:"adminArea1Code,omitempty"` // Short name of the first level admin area. Eg: MA
+	AdminArea2       string `json:"adminArea2,omitempty"`
+	AdminArea3       string `json:"adminArea3,omitempty"`
+	PostalCode       string `json:"postalCode,omitempty"`
+	Country          string `json:"country,omitempty"`
+	CountryCode      string `json:"countryCode,omitempty"` // ISO 3166-1 alpha-2 code. Eg: US
+	formattedAddress string
+}
+
+// ParseAddress fills an Address from address components. The first component of each type wins
+func ParseAddress(components []AddressComponent) Address {
+	var a Address
+	var postalTown string
+	set := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	for _, c := range components {
+		for _, t := range c.Types {
+			switch t {
+			case "street_number":
+				set(&a.StreetNumber, c.LongName)
+			case "route":
+				set(&a.Route, c.LongName)
+			case "sublocality", "sublocality_level_1":
+				set(&a.Sublocality, c.LongName)
+			case "locality":
+				set(&a.Locality, c.LongName)
+			case "postal_town":
+				set(&postalTown, c.LongName)
+			case "administrative_area_level_1":
+				set(&a.AdminArea1, c.LongName)
+				set(&a.AdminArea1Code, c.ShortName)
+			case "administrative_area_level_2":
+				set(&a.AdminArea2, c.LongName)
+			case "administrative_area_level_3":
+				set(&a.AdminArea3, c.LongName)
+			case "postal_code":
+				set(&a.PostalCode, c.LongName)
+			case "country":
+				set(&a.Country, c.LongName)
+				set(&a.CountryCode, strings.ToUpper(c.ShortName))
+			}
+		}
+	}
+	set(&a.Locality, postalTown)
+	return a
+}
+
+// Address parses the result's address components. Format falls back to the formatted address Google returned
+func (r GeocodingResult) Address() Address {
+	a := ParseAddress(r.AddressComponents)
+	a.formattedAddress = r.FormattedAddress
+	return a
+}
+
+// Address parses the place's address components, which need the addressComponents field mask
+func (p Place) Address() Address {
+	components := make([]AddressComponent, 0, len(p.AddressComponents))
+	for _, c := range p.AddressComponents {
+		components = append(components, AddressComponent{LongName: c.LongText, ShortName: c.ShortText, Types: c.Types})
+	}
+	a := ParseAddress(components)
+	a.formattedAddress = p.FormattedAddress
+	return a
+}
+
+// How a country orders the parts of an address
+type addressStyle struct {
+	numberAfterRoute bool // Eg: Unter den Linden 77
+	postalBeforeCity bool // Eg: 10117 Berlin
+	withAdminArea    bool // Eg: Boston, MA 02108
+}
+
+var addressStyles = map[string]addressStyle{
+	"US": {withAdminArea: true},
+	"CA": {withAdminArea: true},
+	"AU": {withAdminArea: true},
+	"IN": {withAdminArea: true},
+	"BR": {numberAfterRoute: true, withAdminArea: true},
+	"MX": {numberAfterRoute: true, postalBeforeCity: true, withAdminArea: true},
+	"GB": {},
+	"IE": {},
+	"FR": {postalBeforeCity: true},
+	"DE": {numberAfterRoute: true, postalBeforeCity: true},
+	"AT": {numberAfterRoute: true, postalBeforeCity: true},
+	"CH": {numberAfterRoute: true, postalBeforeCity: true},
+	"NL": {numberAfterRoute: true, postalBeforeCity: true},
+	"BE": {numberAfterRoute: true, postalBeforeCity: true},
+	"ES": {numberAfterRoute: true, postalBeforeCity: true},
+	"IT": {numberAfterRoute: true, postalBeforeCity: true, withAdminArea: true},
+	"PT": {numberAfterRoute: true, postalBeforeCity: true},
+	"PL": {numberAfterRoute: true, postalBeforeCity: true},
+	"CZ": {numberAfterRoute: true, postalBeforeCity: true},
+	"DK": {numberAfterRoute: true, postalBeforeCity: true},
+	"NO": {numberAfterRoute: true, postalBeforeCity: true},
+	"SE": {numberAfterRoute: true, postalBeforeCity: true},
+	"FI": {numberAfterRoute: true, postalBeforeCity: true},
+}
+
+// Format writes the address on one line in the order used by its country. Eg:
+// "1 Beacon Street, Boston, MA 02108, United States" or "Unter den Linden 77, 10117 Berlin, Germany".
+// When no street or city could be parsed the formatted address from Google is returned instead
+func (a Address) Format() string {
+	if a.Route == "" && a.Locality == "" && a.formattedAddress != "" {
+		return a.formattedAddress
+	}
+	style := addressStyles[a.CountryCode] // Unknown countries get the zero style: street number first, city then postal code
+	street := joinNonEmpty(" ", a.StreetNumber, a.Route)
+	if style.numberAfterRoute {
+		street = joinNonEmpty(" ", a.Route, a.StreetNumber)
+	}
+	city := a.Locality
+	if city == "" {
+		city = a.AdminArea2
+	}
+	var region string
+	switch {
+	case style.withAdminArea && style.postalBeforeCity:
+		region = joinNonEmpty(", ", joinNonEmpty(" ", a.PostalCode, city), a.adminArea())
+	case style.withAdminArea:
+		region = joinNonEmpty(", ", city, joinNonEmpty(" ", a.adminArea(), a.PostalCode))
+	case style.postalBeforeCity:
+		region = joinNonEmpty(" ", a.PostalCode, city)
+	default:
+		region = joinNonEmpty(" ", city, a.PostalCode)
+	}
+	return joinNonEmpty(", ", street, a.Sublocality, region, a.Country)
+}
+
+// SearchLocality returns the city, region and country to append to a text search so it stays
+// within the user's city. Eg: "Boston, MA, United States"
+func (a Address) SearchLocality() string {
+	city := a.Locality
+	if city == "" {
+		city = a.Sublocality
+	}
+	if city == "" {
+		city = a.AdminArea2
+	}
+	if city == "" {
+		return ""
+	}
+	region := a.adminArea()
+	if region == city { // city states such as Singapore
+		region = ""
+	}
+	return joinNonEmpty(", ", city, region, a.Country)
+}
+
+// Admin area short name when it is an abbreviation (MA), otherwise the long name
+func (a Address) adminArea() string {
+	if a.AdminArea1Code != "" && len(a.AdminArea1Code) <= 3 {
+		return a.AdminArea1Code
+	}
+	return a.AdminArea1
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	return strings.Join(slices.DeleteFunc(parts, func(s string) bool { return s == "" }), sep)
+}
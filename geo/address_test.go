@@ -0,0 +1,77 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func component(long, short string, types ...string) AddressComponent {
+	return AddressComponent{LongName: long, ShortName: short, Types: types}
+}
+
+var bostonComponents = []AddressComponent{
+	component("1", "1", "street_number"),
+	component("Beacon Street", "Beacon St", "route"),
+	component("Downtown", "Downtown", "neighborhood", "political"),
+	component("Boston", "Boston", "locality", "political"),
+	component("Suffolk County", "Suffolk County", "administrative_area_level_2", "political"),
+	component("Massachusetts", "MA", "administrative_area_level_1", "political"),
+	component("United States", "us", "country", "political"),
+	component("02108", "02108", "postal_code"),
+}
+
+// Testing components are mapped to address fields
+func Test_ParseAddress(t *testing.T) {
+	a := ParseAddress(bostonComponents)
+	assert.Equal(t, "1", a.StreetNumber)
+	assert.Equal(t, "Beacon Street", a.Route)
+	assert.Equal(t, "Boston", a.Locality)
+	assert.Equal(t, "Massachusetts", a.AdminArea1)
+	assert.Equal(t, "MA", a.AdminArea1Code)
+	assert.Equal(t, "Suffolk County", a.AdminArea2)
+	assert.Equal(t, "02108", a.PostalCode)
+	assert.Equal(t, "US", a.CountryCode)
+	assert.Empty(t, a.Sublocality)
+
+	// UK addresses have a postal town rather than a locality
+	london := ParseAddress([]AddressComponent{
+		component("10", "10", "street_number"),
+		component("Downing Street", "Downing St", "route"),
+		component("Westminster", "Westminster", "sublocality_level_1", "sublocality", "political"),
+		component("London", "London", "postal_town"),
+		component("England", "England", "administrative_area_level_1", "political"),
+		component("United Kingdom", "GB", "country", "political"),
+		component("SW1A 2AA", "SW1A 2AA", "postal_code"),
+	})
+	assert.Equal(t, "London", london.Locality)
+	assert.Equal(t, "Westminster", london.Sublocality)
+}
+
+// Testing addresses are written in each country's order
+func Test_Address_Format(t *testing.T) {
+	assert.Equal(t, "1 Beacon Street, Boston, MA 02108, United States", ParseAddress(bostonComponents).Format())
+
+	berlin := Address{StreetNumber: "77", Route: "Unter den Linden", Locality: "Berlin", AdminArea1: "Berlin", PostalCode: "10117", Country: "Germany", CountryCode: "DE"}
+	assert.Equal(t, "Unter den Linden 77, 10117 Berlin, Germany", berlin.Format())
+
+	paris := Address{StreetNumber: "5", Route: "Avenue Anatole France", Locality: "Paris", PostalCode: "75007", Country: "France", CountryCode: "FR"}
+	assert.Equal(t, "5 Avenue Anatole France, 75007 Paris, France", paris.Format())
+
+	london := Address{StreetNumber: "10", Route: "Downing Street", Locality: "London", PostalCode: "SW1A 2AA", Country: "United Kingdom", CountryCode: "GB"}
+	assert.Equal(t, "10 Downing Street, London SW1A 2AA, United Kingdom", london.Format())
+
+	unknown := Address{Route: "Main Road", Locality: "Somewhere", Country: "Nowhere", CountryCode: "ZZ"}
+	assert.Equal(t, "Main Road, Somewhere, Nowhere", unknown.Format())
+
+	result := GeocodingResult{FormattedAddress: "Kenya", AddressComponents: []AddressComponent{component("Kenya", "KE", "country")}}
+	assert.Equal(t, "Kenya", result.Address().Format())
+}
+
+// Testing the locality appended to text searches
+func Test_Address_SearchLocality(t *testing.T) {
+	assert.Equal(t, "Boston, MA, United States", ParseAddress(bostonComponents).SearchLocality())
+	assert.Equal(t, "Berlin, Germany", Address{Locality: "Berlin", AdminArea1: "Berlin", Country: "Germany"}.SearchLocality())
+	assert.Equal(t, "Brooklyn, NY, United States", Address{Sublocality: "Brooklyn", AdminArea1Code: "NY", Country: "United States"}.SearchLocality())
+	assert.Equal(t, "", Address{Country: "Kenya"}.SearchLocality())
+}
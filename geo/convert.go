@@ -0,0 +1,56 @@
+package geo
+
+import "github.com/geolocate/geo/geometry"
+
+// Conversions between the Geocoding API (LatLng), Places API (Location) and geometry types
+
+// Point converts a LatLng for use with the geometry package
+func (l LatLng) Point() geometry.Point {
+	return geometry.Point{Lat: l.Lat, Lng: l.Lng}
+}
+
+// Location converts a Geocoding API LatLng into a Places API Location
+func (l LatLng) Location() Location {
+	return Location{Latitude: l.Lat, Longitude: l.Lng}
+}
+
+// Point converts a Location for use with the geometry package
+func (l Location) Point() geometry.Point {
+	return geometry.Point{Lat: l.Latitude, Lng: l.Longitude}
+}
+
+// LatLng converts a Places API Location into a Geocoding API LatLng
+func (l Location) LatLng() LatLng {
+	return LatLng{Lat: l.Latitude, Lng: l.Longitude}
+}
+
+func LatLngFromPoint(p geometry.Point) LatLng {
+	return LatLng{Lat: p.Lat, Lng: p.Lng}
+}
+
+func LocationFromPoint(p geometry.Point) Location {
+	return Location{Latitude: p.Lat, Longitude: p.Lng}
+}
+
+// Geometry converts bounds such as a geocoded viewport for use with the geometry package
+func (b LatLngBounds) Geometry() geometry.Bounds {
+	return geometry.Bounds{SW: b.SouthWest.Point(), NE: b.NorthEast.Point()}
+}
+
+// Rectangle converts bounds such as a geocoded viewport into a Places API rectangle
+func (b LatLngBounds) Rectangle() Rectangle {
+	return Rectangle{Low: b.SouthWest.Location(), High: b.NorthEast.Location()}
+}
+
+// Geometry converts a Places API rectangle for use with the geometry package
+func (r Rectangle) Geometry() geometry.Bounds {
+	return geometry.Bounds{SW: r.Low.Point(), NE: r.High.Point()}
+}
+
+func LatLngBoundsFromGeometry(b geometry.Bounds) LatLngBounds {
+	return LatLngBounds{SouthWest: LatLngFromPoint(b.SW), NorthEast: LatLngFromPoint(b.NE)}
+}
+
+func RectangleFromGeometry(b geometry.Bounds) Rectangle {
+	return Rectangle{Low: LocationFromPoint(b.SW), High: LocationFromPoint(b.NE)}
+}
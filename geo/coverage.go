@@ -0,0 +1,285 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/geolocate/geo/geometry"
+)
+
+// Places Nearby Search returns at most 20 results per call
+const maxNearbyResults = 20
+
+// MaxNearbyRadius is the largest circle radius in meters Places Nearby Search accepts
+const MaxNearbyRadius = 50000
+
+// Most cells an area may be split into before it is searched, so a huge area fails fast instead of
+// spending the quota
+const maxCoverageRoots = 64
+
+// SearchArea is a region that CoverageSearch can cover with nearby searches.
+type SearchArea interface {
+	// Contains reports whether a location lies inside the area
+	Contains(l Location) bool
+	// Bounds is the smallest rectangle enclosing the area
+	Bounds() Rectangle
+}
+
+// CoverageOptions tune how CoverageSearch splits an area into cells.
+type CoverageOptions struct {
+	// MaxDepth is the number of times a saturated cell may be split into quadrants. Defaults to 4
+	MaxDepth int
+	// MinRadius in meters below which a cell is never split. Defaults to 100
+	MinRadius float64
+	// Concurrency is the number of cells searched at once. Defaults to 4.
+	// Requests still share the client's rate limiter
+	Concurrency int
+	// OnPlace is called with each distinct place inside the area as soon as it is found, so results
+	// can be streamed before the search ends. Calls are never concurrent
+	OnPlace func(Place)
+}
+
+// CoverageStats describes the work a CoverageSearch did.
+type CoverageStats struct {
+	Calls     int `json:"calls"`     // Nearby Search calls made
+	Saturated int `json:"saturated"` // Cells that hit the result cap and were split
+	Truncated int `json:"truncated"` // Saturated cells that could not be split further. Places may be missing from these
+	MaxDepth  int `json:"maxDepth"`  // Deepest level of subdivision reached
+}
+
+// CoverageResponse holds every distinct place found inside the area.
+type CoverageResponse struct {
+	Places []Place       `json:"places"`
+	Stats  CoverageStats `json:"stats"`
+}
+
+// A cell is a rectangle searched through the circle that circumscribes it
+type coverageCell struct {
+	rect   Rectangle
+	circle Circle
+	depth  int
+}
+
+// CoverageSearch finds all places inside area, working around the 20 result cap of
+// Nearby Search. Whenever a cell returns a full page of results it is split into four
+// quadrants that are searched in turn. Cells are searched concurrently, results are
+// deduplicated by place ID and filtered to those inside area. r supplies the type filters;
+// its LocationRestriction is replaced for every cell.
+func (c *GeoClient) CoverageSearch(ctx context.Context, area SearchArea, r *NearbySearchRequest, h *PlacesHeader, opts CoverageOptions) (CoverageResponse, error) {
+	if area == nil {
+		return CoverageResponse{}, errors.New("maps: Required field area missing")
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 4
+	}
+	if opts.MinRadius <= 0 {
+		opts.MinRadius = 100
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	pageSize := r.MaxResultCount
+	if pageSize <= 0 || pageSize > maxNearbyResults {
+		pageSize = maxNearbyResults
+	}
+	// Place ID is always requested as it is used to deduplicate results across cells
+	header := PlacesHeader{FieldMaskPrefix: true}
+	if h != nil {
+		header = *h
+	}
+	header.FieldMasks = withFieldMask(header.FieldMasks, PlaceFieldMaskPlaceID)
+
+	roots, err := rootCells(area)
+	if err != nil {
+		return CoverageResponse{}, err
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		seen     = map[string]bool{}
+		resp     CoverageResponse
+		sem      = make(chan struct{}, opts.Concurrency)
+	)
+	var search func(cell coverageCell)
+	search = func(cell coverageCell) {
+		defer wg.Done()
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		req := *r
+		req.MaxResultCount = pageSize
+		req.LocationRestriction = &LocationRestriction{Circle: cell.circle}
+		page, err := c.NearbySearch(ctx, &req, &header)
+		<-sem
+
+		mu.Lock()
+		defer mu.Unlock()
+		resp.Stats.Calls++
+		resp.Stats.MaxDepth = max(resp.Stats.MaxDepth, cell.depth)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+			return
+		}
+		for _, place := range page.Places {
+			if !seen[place.Id] && area.Contains(place.Location) {
+				seen[place.Id] = true
+				resp.Places = append(resp.Places, place)
+				if opts.OnPlace != nil {
+					opts.OnPlace(place)
+				}
+			}
+		}
+		if len(page.Places) < int(pageSize) {
+			return
+		}
+		if cell.depth >= opts.MaxDepth || cell.circle.Radius/2 < int64(opts.MinRadius) {
+			resp.Stats.Truncated++
+			return
+		}
+		resp.Stats.Saturated++
+		for _, quadrant := range cell.rect.quadrants() {
+			if !areaIntersects(area, quadrant) {
+				continue
+			}
+			wg.Add(1)
+			go search(coverageCell{rect: quadrant, circle: quadrant.circumcircle(), depth: cell.depth + 1})
+		}
+	}
+
+	for _, root := range roots {
+		wg.Add(1)
+		go search(root)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return CoverageResponse{}, firstErr
+	}
+	// Cells waiting for their turn stop without an error when the caller gives up, so the places found
+	// so far are not complete
+	if err := parent.Err(); err != nil {
+		return CoverageResponse{}, err
+	}
+	return resp, nil
+}
+
+// Cells the search starts from. The area is split into quadrants until every cell's circle is within
+// MaxNearbyRadius, as Google rejects larger ones
+func rootCells(area SearchArea) ([]coverageCell, error) {
+	if circle, ok := area.(Circle); ok && circle.Radius <= MaxNearbyRadius {
+		return []coverageCell{{rect: area.Bounds(), circle: circle}}, nil // a circle is searched as is rather than through its bounding box
+	}
+	var cells []coverageCell
+	pending := []Rectangle{area.Bounds()}
+	for len(pending) > 0 {
+		rect := pending[0]
+		pending = pending[1:]
+		if circle := rect.circumcircle(); circle.Radius <= MaxNearbyRadius {
+			cells = append(cells, coverageCell{rect: rect, circle: circle})
+			continue
+		}
+		for _, quadrant := range rect.quadrants() {
+			if areaIntersects(area, quadrant) {
+				pending = append(pending, quadrant)
+			}
+		}
+		if len(cells)+len(pending) > maxCoverageRoots {
+			return nil, fmt.Errorf("maps: area is too large, it needs more than %d searches of %d m to cover", maxCoverageRoots, MaxNearbyRadius)
+		}
+	}
+	return cells, nil
+}
+
+// Areas with an exact overlap test, such as polygons, let CoverageSearch skip cells
+// that only touch the enclosing rectangle
+type rectangleIntersector interface {
+	IntersectsRectangle(r Rectangle) bool
+}
+
+func areaIntersects(area SearchArea, r Rectangle) bool {
+	if a, ok := area.(rectangleIntersector); ok {
+		return a.IntersectsRectangle(r)
+	}
+	return area.Bounds().Intersects(r)
+}
+
+// Append mask to masks unless it is already present
+func withFieldMask(masks []PlaceFieldMask, mask PlaceFieldMask) []PlaceFieldMask {
+	for _, m := range masks {
+		if m == mask {
+			return masks
+		}
+	}
+	return append(append([]PlaceFieldMask{}, masks...), mask)
+}
+
+// Contains reports whether l lies within the circle
+func (c Circle) Contains(l Location) bool {
+	return distanceMeters(c.Center, l) <= float64(c.Radius)
+}
+
+// Bounds returns the rectangle enclosing the circle. It crosses the antimeridian if the circle does
+func (c Circle) Bounds() Rectangle {
+	return RectangleFromGeometry(geometry.BoundsAround(c.Center.Point(), float64(c.Radius)))
+}
+
+// IntersectsRectangle reports whether the circle overlaps r
+func (c Circle) IntersectsRectangle(r Rectangle) bool {
+	return geometry.CircleIntersectsBounds(c.Center.Point(), float64(c.Radius), r.Geometry())
+}
+
+// Contains reports whether l lies within the rectangle. Low longitude greater than high
+// longitude means the rectangle crosses the antimeridian
+func (r Rectangle) Contains(l Location) bool {
+	return r.Geometry().Contains(l.Point())
+}
+
+// Bounds of a rectangle is the rectangle itself
+func (r Rectangle) Bounds() Rectangle {
+	return r
+}
+
+// Intersects reports whether two rectangles overlap
+func (r Rectangle) Intersects(o Rectangle) bool {
+	return r.Geometry().Intersects(o.Geometry())
+}
+
+func (r Rectangle) center() Location {
+	return LocationFromPoint(r.Geometry().Center())
+}
+
+// Smallest circle around the rectangle. Radius is rounded up so corners stay inside
+func (r Rectangle) circumcircle() Circle {
+	center := r.center()
+	radius := max(distanceMeters(center, r.Low), distanceMeters(center, r.High),
+		distanceMeters(center, Location{Latitude: r.Low.Latitude, Longitude: r.High.Longitude}),
+		distanceMeters(center, Location{Latitude: r.High.Latitude, Longitude: r.Low.Longitude}))
+	return Circle{Center: center, Radius: int64(math.Ceil(radius))}
+}
+
+// Split the rectangle into four equal quadrants
+func (r Rectangle) quadrants() []Rectangle {
+	mid := r.center()
+	return []Rectangle{
+		{Low: r.Low, High: mid},
+		{Low: Location{Latitude: r.Low.Latitude, Longitude: mid.Longitude}, High: Location{Latitude: mid.Latitude, Longitude: r.High.Longitude}},
+		{Low: Location{Latitude: mid.Latitude, Longitude: r.Low.Longitude}, High: Location{Latitude: r.High.Latitude, Longitude: mid.Longitude}},
+		{Low: mid, High: r.High},
+	}
+}
+
+// Great circle distance between two locations
+func distanceMeters(a, b Location) float64 {
+	return geometry.Haversine(a.Point(), b.Point())
+}
@@ -0,0 +1,156 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/geolocate/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// Fake Places nearby search over a fixed set of places. It returns up to maxResultCount
+// places inside the requested circle, the way Google caps results
+func newNearbySearchServer(t *testing.T, places []Place) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req NearbySearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var resp PlacesSearchResponse
+		for _, p := range places {
+			if len(resp.Places) == int(req.MaxResultCount) {
+				break
+			}
+			if req.LocationRestriction.Circle.Contains(p.Location) {
+				resp.Places = append(resp.Places, p)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+// A 10x10 grid of places roughly 200m apart around Halifax
+func gridPlaces() []Place {
+	var places []Place
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			places = append(places, Place{
+				Id:       fmt.Sprintf("%d-%d", i, j),
+				Location: Location{Latitude: 44.64 + float64(i)*0.002, Longitude: -63.58 + float64(j)*0.0025},
+			})
+		}
+	}
+	return places
+}
+
+// Testing CoverageSearch finds more places than a single call can return
+func Test_CoverageSearch_Rectangle(t *testing.T) {
+	places := gridPlaces()
+	srv := newNearbySearchServer(t, places)
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	area := Rectangle{Low: Location{Latitude: 44.639, Longitude: -63.581}, High: Location{Latitude: 44.659, Longitude: -63.557}}
+	req := NearbySearchRequest{IncludedTypes: []PlaceType{Restaurant}}
+	resp, err := testGeoClient.CoverageSearch(context.Background(), area, &req, &PlacesHeader{FieldMaskPrefix: true}, CoverageOptions{MaxDepth: 6})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Places, len(places))
+	assert.Greater(t, resp.Stats.Calls, 1)
+	assert.Greater(t, resp.Stats.Saturated, 0)
+	assert.Equal(t, 0, resp.Stats.Truncated)
+	assert.Nil(t, req.LocationRestriction)
+}
+
+// Testing CoverageSearch keeps only places inside a circular area, passing each to OnPlace as it is found
+func Test_CoverageSearch_Circle(t *testing.T) {
+	places := gridPlaces()
+	srv := newNearbySearchServer(t, places)
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	area := Circle{Center: Location{Latitude: 44.649, Longitude: -63.569}, Radius: 600}
+	want := 0
+	for _, p := range places {
+		if area.Contains(p.Location) {
+			want++
+		}
+	}
+	var streamed []Place
+	resp, err := testGeoClient.CoverageSearch(context.Background(), area, &NearbySearchRequest{}, nil,
+		CoverageOptions{MaxDepth: 6, Concurrency: 2, OnPlace: func(p Place) { streamed = append(streamed, p) }})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Places, want)
+	assert.Equal(t, resp.Places, streamed)
+	for _, p := range resp.Places {
+		assert.True(t, area.Contains(p.Location))
+	}
+}
+
+// Testing CoverageSearch reports cells it could not split any further
+func Test_CoverageSearch_Truncated(t *testing.T) {
+	srv := newNearbySearchServer(t, gridPlaces())
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	area := Circle{Center: Location{Latitude: 44.649, Longitude: -63.569}, Radius: 2000}
+	resp, err := testGeoClient.CoverageSearch(context.Background(), area, &NearbySearchRequest{}, nil, CoverageOptions{MaxDepth: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, resp.Stats.Calls)
+	assert.Greater(t, resp.Stats.Truncated, 0)
+}
+
+// Testing CoverageSearch splits an area too large for one nearby search before searching it
+func Test_CoverageSearch_LargeArea(t *testing.T) {
+	var mu sync.Mutex
+	var radii []int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req NearbySearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		radii = append(radii, req.LocationRestriction.Circle.Radius)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(PlacesSearchResponse{})
+	}))
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	area := Circle{Center: Location{Latitude: 44.649, Longitude: -63.569}, Radius: 80000}
+	resp, err := testGeoClient.CoverageSearch(context.Background(), area, &NearbySearchRequest{}, nil, CoverageOptions{})
+	assert.NoError(t, err)
+	assert.Greater(t, resp.Stats.Calls, 1)
+	assert.Len(t, radii, resp.Stats.Calls)
+	for _, radius := range radii {
+		assert.LessOrEqual(t, radius, int64(MaxNearbyRadius))
+	}
+
+	continent := Rectangle{Low: Location{Latitude: 25, Longitude: -125}, High: Location{Latitude: 49, Longitude: -67}}
+	_, err = testGeoClient.CoverageSearch(context.Background(), continent, &NearbySearchRequest{}, nil, CoverageOptions{})
+	assert.ErrorContains(t, err, "area is too large")
+	assert.Len(t, radii, resp.Stats.Calls)
+}
+
+// Testing CoverageSearch returns the context's error, not partial results, when the caller gives up
+func Test_CoverageSearch_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	places := gridPlaces()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel() // give up once the first cell is searched, while its quadrants wait for their turn
+		json.NewEncoder(w).Encode(PlacesSearchResponse{Places: places[:maxNearbyResults]})
+	}))
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	area := Circle{Center: Location{Latitude: 44.649, Longitude: -63.569}, Radius: 2000}
+	resp, err := testGeoClient.CoverageSearch(ctx, area, &NearbySearchRequest{}, nil, CoverageOptions{Concurrency: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, resp.Places)
+}
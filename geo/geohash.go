@@ -0,0 +1,185 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Geohash cells for indexing places by location. A geohash interleaves longitude and latitude
+// bits, longitude first, and writes them 5 bits per character. Hashes sharing a prefix are close
+// together, so a store can look up everything near a point by cell.
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashMaxPrecision is the longest hash supported. 12 characters is a cell of a few centimeters
+const GeohashMaxPrecision = 12
+
+// Covering an area with more cells than this is refused. Use a lower precision instead
+const maxCoveringCells = 4096
+
+// ErrInvalidGeohash is returned for hashes that are empty, too long or hold characters outside the alphabet
+var ErrInvalidGeohash = errors.New("geohash: invalid hash")
+
+// EncodeGeohash returns the geohash of the given precision (1-12 characters) for a location.
+// Latitude is clipped to [-90, 90] and longitude normalised
+func EncodeGeohash(lat, lng float64, precision int) string {
+	precision = max(1, min(precision, GeohashMaxPrecision))
+	latBits, lngBits := geohashBits(precision)
+	lat = math.Max(-90, math.Min(lat, 90))
+	lng = math.Mod(math.Mod(lng+180, 360)+360, 360)
+	latIdx := min(uint64((lat+90)/180*float64(uint64(1)<<latBits)), uint64(1)<<latBits-1)
+	lngIdx := min(uint64(lng/360*float64(uint64(1)<<lngBits)), uint64(1)<<lngBits-1)
+	return geohashFromIndex(latIdx, lngIdx, precision)
+}
+
+// DecodeGeohash returns the cell a geohash covers
+func DecodeGeohash(hash string) (Rectangle, error) {
+	latIdx, lngIdx, err := geohashIndex(hash)
+	if err != nil {
+		return Rectangle{}, err
+	}
+	return geohashCell(latIdx, lngIdx, len(hash)), nil
+}
+
+// GeohashCenter returns the middle of the cell a geohash covers
+func GeohashCenter(hash string) (Location, error) {
+	cell, err := DecodeGeohash(hash)
+	if err != nil {
+		return Location{}, err
+	}
+	return cell.center(), nil
+}
+
+// GeohashNeighbours returns the cells of the same precision around hash, clockwise from north.
+// Longitude wraps around the antimeridian. Cells at a pole have no neighbours beyond it, so fewer than 8 are returned
+func GeohashNeighbours(hash string) ([]string, error) {
+	latIdx, lngIdx, err := geohashIndex(hash)
+	if err != nil {
+		return nil, err
+	}
+	latBits, lngBits := geohashBits(len(hash))
+	rows, cols := int64(1)<<latBits, int64(1)<<lngBits
+	var neighbours []string
+	for _, d := range [][2]int64{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}} {
+		lat := int64(latIdx) + d[0]
+		if lat < 0 || lat >= rows {
+			continue
+		}
+		lng := (int64(lngIdx) + d[1] + cols) % cols
+		neighbours = append(neighbours, geohashFromIndex(uint64(lat), uint64(lng), len(hash)))
+	}
+	return neighbours, nil
+}
+
+// GeohashCovering returns the cells of the given precision that overlap area, such as a Circle or
+// Rectangle. Cells only touching the enclosing rectangle of areas with an exact overlap test are skipped.
+// It fails when more than 4096 cells would be needed
+func GeohashCovering(area SearchArea, precision int) ([]string, error) {
+	if area == nil {
+		return nil, errors.New("maps: Required field area missing")
+	}
+	if precision < 1 || precision > GeohashMaxPrecision {
+		return nil, fmt.Errorf("geohash: precision must be between 1 and %d", GeohashMaxPrecision)
+	}
+	latBits, lngBits := geohashBits(precision)
+	rows, cols := uint64(1)<<latBits, uint64(1)<<lngBits
+	var cells []string
+	for _, part := range area.Bounds().Geometry().Split() {
+		latLo, latHi := geohashRow(part.SW.Lat, rows), geohashRow(part.NE.Lat, rows)
+		lngLo, lngHi := geohashCol(part.SW.Lng, cols), geohashCol(part.NE.Lng, cols)
+		if part.NE.Lng-part.SW.Lng >= 360 {
+			lngLo, lngHi = 0, cols-1
+		}
+		if count := (latHi - latLo + 1) * (lngHi - lngLo + 1); uint64(len(cells))+count > maxCoveringCells {
+			return nil, fmt.Errorf("geohash: area needs more than %d cells at precision %d", maxCoveringCells, precision)
+		}
+		for lat := latLo; lat <= latHi; lat++ {
+			for lng := lngLo; lng <= lngHi; lng++ {
+				if areaIntersects(area, geohashCell(lat, lng, precision)) {
+					cells = append(cells, geohashFromIndex(lat, lng, precision))
+				}
+			}
+		}
+	}
+	return cells, nil
+}
+
+// Number of latitude and longitude bits in a hash. Longitude takes the extra bit of odd totals
+func geohashBits(precision int) (latBits, lngBits uint) {
+	bits := uint(precision * 5)
+	return bits / 2, bits - bits/2
+}
+
+// Row and column holding a latitude or longitude, clamped to the grid
+func geohashRow(lat float64, rows uint64) uint64 {
+	return uint64(max(0, min(float64(rows-1), math.Floor((lat+90)/180*float64(rows)))))
+}
+
+func geohashCol(lng float64, cols uint64) uint64 {
+	return uint64(max(0, min(float64(cols-1), math.Floor((lng+180)/360*float64(cols)))))
+}
+
+func geohashCell(latIdx, lngIdx uint64, precision int) Rectangle {
+	latBits, lngBits := geohashBits(precision)
+	latStep, lngStep := 180/float64(uint64(1)<<latBits), 360/float64(uint64(1)<<lngBits)
+	return Rectangle{
+		Low:  Location{Latitude: float64(latIdx)*latStep - 90, Longitude: float64(lngIdx)*lngStep - 180},
+		High: Location{Latitude: float64(latIdx+1)*latStep - 90, Longitude: float64(lngIdx+1)*lngStep - 180},
+	}
+}
+
+// Interleave row and column bits into hash characters
+func geohashFromIndex(latIdx, lngIdx uint64, precision int) string {
+	latBits, lngBits := geohashBits(precision)
+	var sb strings.Builder
+	var char uint64
+	for bit := 0; bit < precision*5; bit++ {
+		var b uint64
+		if bit%2 == 0 {
+			lngBits--
+			b = lngIdx >> lngBits & 1
+		} else {
+			latBits--
+			b = latIdx >> latBits & 1
+		}
+		char = char<<1 | b
+		if bit%5 == 4 {
+			sb.WriteByte(geohashAlphabet[char])
+			char = 0
+		}
+	}
+	return sb.String()
+}
+
+// Split hash characters back into row and column
+func geohashIndex(hash string) (latIdx, lngIdx uint64, err error) {
+	if hash == "" || len(hash) > GeohashMaxPrecision {
+		return 0, 0, ErrInvalidGeohash
+	}
+	bit := 0
+	for i := 0; i < len(hash); i++ {
+		c := strings.IndexByte(geohashAlphabet, toLowerASCII(hash[i]))
+		if c < 0 {
+			return 0, 0, ErrInvalidGeohash
+		}
+		for shift := 4; shift >= 0; shift-- {
+			b := uint64(c>>shift) & 1
+			if bit%2 == 0 {
+				lngIdx = lngIdx<<1 | b
+			} else {
+				latIdx = latIdx<<1 | b
+			}
+			bit++
+		}
+	}
+	return latIdx, lngIdx, nil
+}
+
+func toLowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
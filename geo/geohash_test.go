@@ -0,0 +1,73 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing against well known hashes and round trips
+func Test_EncodeGeohash(t *testing.T) {
+	assert.Equal(t, "u4pruydqqvj", EncodeGeohash(57.64911, 10.40744, 11))
+	assert.Equal(t, "drt2z", EncodeGeohash(42.3601, -71.0589, 5))
+	assert.Equal(t, "s", EncodeGeohash(0, 0, 0)) // precision is clamped to 1
+	assert.Equal(t, "zzzzzz", EncodeGeohash(90, 180-1e-9, 6))
+	assert.Equal(t, EncodeGeohash(10, -170, 6), EncodeGeohash(10, 190, 6))
+
+	center, err := GeohashCenter("EZS42")
+	assert.NoError(t, err)
+	assert.InDelta(t, 42.605, center.Latitude, 0.01)
+	assert.InDelta(t, -5.603, center.Longitude, 0.01)
+
+	cell, err := DecodeGeohash("u4pruydqqvj")
+	assert.NoError(t, err)
+	assert.True(t, cell.Contains(Location{Latitude: 57.64911, Longitude: 10.40744}))
+	for _, hash := range []string{"", "u4pruydqqvjxx", "abc"} {
+		_, err := DecodeGeohash(hash)
+		assert.ErrorIs(t, err, ErrInvalidGeohash, hash)
+	}
+}
+
+// Testing neighbours share edges, wrap the antimeridian and stop at the poles
+func Test_GeohashNeighbours(t *testing.T) {
+	neighbours, err := GeohashNeighbours("dqcjq")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dqcjw", "dqcjx", "dqcjr", "dqcjp", "dqcjn", "dqcjj", "dqcjm", "dqcjt"}, neighbours)
+
+	east, err := GeohashNeighbours(EncodeGeohash(0, 179.99, 4))
+	assert.NoError(t, err)
+	assert.Contains(t, east, EncodeGeohash(0, -179.99, 4))
+
+	pole, err := GeohashNeighbours(EncodeGeohash(89.99, 0, 4))
+	assert.NoError(t, err)
+	assert.Len(t, pole, 5)
+}
+
+// Testing covering cells overlap the area and include every location inside it
+func Test_GeohashCovering(t *testing.T) {
+	circle := Circle{Center: Location{Latitude: 42.3601, Longitude: -71.0589}, Radius: 2000}
+	cells, err := GeohashCovering(circle, 6)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cells)
+	box, err := GeohashCovering(circle.Bounds(), 6)
+	assert.NoError(t, err)
+	assert.Less(t, len(cells), len(box)) // corner cells of the box miss the circle
+	for i := -20; i <= 20; i++ {
+		for j := -20; j <= 20; j++ {
+			l := Location{Latitude: 42.3601 + float64(i)*0.001, Longitude: -71.0589 + float64(j)*0.00125}
+			if circle.Contains(l) {
+				assert.Contains(t, cells, EncodeGeohash(l.Latitude, l.Longitude, 6))
+			}
+		}
+	}
+
+	fiji, err := GeohashCovering(Circle{Center: Location{Latitude: -17.7, Longitude: 179.95}, Radius: 20000}, 4)
+	assert.NoError(t, err)
+	assert.Contains(t, fiji, EncodeGeohash(-17.7, 179.95, 4))
+	assert.Contains(t, fiji, EncodeGeohash(-17.7, -179.95, 4))
+
+	_, err = GeohashCovering(circle, 0)
+	assert.Error(t, err)
+	_, err = GeohashCovering(Circle{Center: circle.Center, Radius: 500000}, 7)
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package geometry
+
+import "math"
+
+// Bounds is a latitude/longitude aligned box. When SW.Lng is greater than NE.Lng the box
+// crosses the antimeridian, running east from SW.Lng past 180 to NE.Lng.
+type Bounds struct {
+	SW Point `json:"southwest"`
+	NE Point `json:"northeast"`
+}
+
+// BoundsAround returns the smallest box containing the circle of radius meters around center.
+// Circles reaching a pole span every longitude. Boxes crossing the antimeridian wrap.
+func BoundsAround(center Point, radius float64) Bounds {
+	dLat := toDegrees(radius / EarthRadius)
+	south, north := center.Lat-dLat, center.Lat+dLat
+	if south <= -90 || north >= 90 {
+		return Bounds{SW: Point{Lat: max(south, -90), Lng: -180}, NE: Point{Lat: min(north, 90), Lng: 180}}
+	}
+	// Widest longitude offset of the circle, found at the latitude where its edge is tangent to a meridian
+	dLng := toDegrees(math.Asin(math.Sin(radius/EarthRadius) / math.Cos(toRadians(center.Lat))))
+	if dLng >= 180 || math.IsNaN(dLng) {
+		return Bounds{SW: Point{Lat: south, Lng: -180}, NE: Point{Lat: north, Lng: 180}}
+	}
+	return Bounds{
+		SW: Point{Lat: south, Lng: NormalizeLng(center.Lng - dLng)},
+		NE: Point{Lat: north, Lng: NormalizeLng(center.Lng + dLng)},
+	}
+}
+
+// CrossesAntimeridian reports whether the box wraps past longitude 180
+func (b Bounds) CrossesAntimeridian() bool {
+	return b.SW.Lng > b.NE.Lng
+}
+
+// LngSpan returns the width of the box in degrees of longitude
+func (b Bounds) LngSpan() float64 {
+	if b.CrossesAntimeridian() {
+		return 360 - b.SW.Lng + b.NE.Lng
+	}
+	return b.NE.Lng - b.SW.Lng
+}
+
+// Contains reports whether p lies inside the box, edges included
+func (b Bounds) Contains(p Point) bool {
+	if p.Lat < b.SW.Lat || p.Lat > b.NE.Lat {
+		return false
+	}
+	return b.containsLng(p.Lng)
+}
+
+func (b Bounds) containsLng(lng float64) bool {
+	if b.CrossesAntimeridian() {
+		return lng >= b.SW.Lng || lng <= b.NE.Lng
+	}
+	return lng >= b.SW.Lng && lng <= b.NE.Lng
+}
+
+// Center returns the middle of the box
+func (b Bounds) Center() Point {
+	return Point{
+		Lat: (b.SW.Lat + b.NE.Lat) / 2,
+		Lng: NormalizeLng(b.SW.Lng + b.LngSpan()/2),
+	}
+}
+
+// Split divides a box crossing the antimeridian into its east and west parts, neither of
+// which crosses. A box that does not cross is returned as is.
+func (b Bounds) Split() []Bounds {
+	if !b.CrossesAntimeridian() {
+		return []Bounds{b}
+	}
+	return []Bounds{
+		{SW: b.SW, NE: Point{Lat: b.NE.Lat, Lng: 180}},
+		{SW: Point{Lat: b.SW.Lat, Lng: -180}, NE: b.NE},
+	}
+}
+
+// Intersects reports whether two boxes overlap
+func (b Bounds) Intersects(o Bounds) bool {
+	if b.SW.Lat > o.NE.Lat || o.SW.Lat > b.NE.Lat {
+		return false
+	}
+	for _, x := range b.Split() {
+		for _, y := range o.Split() {
+			if x.SW.Lng <= y.NE.Lng && y.SW.Lng <= x.NE.Lng {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Extend returns the smallest box containing both b and p. Longitude grows in whichever
+// direction adds less width, so boxes may come to cross the antimeridian
+func (b Bounds) Extend(p Point) Bounds {
+	b.SW.Lat = min(b.SW.Lat, p.Lat)
+	b.NE.Lat = max(b.NE.Lat, p.Lat)
+	if b.containsLng(p.Lng) {
+		return b
+	}
+	east := math.Mod(p.Lng-b.NE.Lng+360, 360) // degrees to grow eastwards
+	west := math.Mod(b.SW.Lng-p.Lng+360, 360) // degrees to grow westwards
+	if east <= west {
+		b.NE.Lng = p.Lng
+	} else {
+		b.SW.Lng = p.Lng
+	}
+	return b
+}
+
+// CircleIntersectsBounds reports whether the circle of radius meters around center overlaps the box
+func CircleIntersectsBounds(center Point, radius float64, b Bounds) bool {
+	if b.Contains(center) {
+		return true
+	}
+	return Haversine(center, b.nearest(center)) <= radius
+}
+
+// The point of the box closest to p, measured along the box edges
+func (b Bounds) nearest(p Point) Point {
+	lat := math.Max(b.SW.Lat, math.Min(b.NE.Lat, p.Lat))
+	if b.containsLng(p.Lng) {
+		return Point{Lat: lat, Lng: p.Lng}
+	}
+	// Outside the longitude range. The closer of the west and east edges, going round the globe either way
+	toWest := math.Mod(b.SW.Lng-p.Lng+360, 360)
+	toEast := math.Mod(p.Lng-b.NE.Lng+360, 360)
+	edge := b.NE.Lng
+	if toWest < toEast {
+		edge = b.SW.Lng
+	}
+	// Approximate the closest point on the edge meridian by its two ends and the point level with p
+	candidates := []Point{{Lat: b.SW.Lat, Lng: edge}, {Lat: b.NE.Lat, Lng: edge}, {Lat: lat, Lng: edge}}
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if Haversine(p, c) < Haversine(p, best) {
+			best = c
+		}
+	}
+	return best
+}
@@ -0,0 +1,166 @@
+// Package geometry provides offline geodesic calculations on latitude/longitude points:
+// distances, bearings, destinations and bounding boxes. It has no knowledge of the
+// Google APIs. Package geo converts its LatLng, Location and bounds types to and from these.
+package geometry
+
+import (
+	"errors"
+	"math"
+)
+
+// EarthRadius is the mean earth radius in meters used by the spherical formulas
+const EarthRadius = 6371008.8
+
+// WGS84 ellipsoid used by Vincenty's formula
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+)
+
+// ErrNoConvergence is returned by Vincenty for nearly antipodal points
+var ErrNoConvergence = errors.New("geometry: vincenty formula failed to converge")
+
+// Point is a latitude/longitude pair in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+func toRadians(d float64) float64 { return d * math.Pi / 180 }
+func toDegrees(r float64) float64 { return r * 180 / math.Pi }
+
+// NormalizeLng wraps a longitude into [-180, 180)
+func NormalizeLng(lng float64) float64 {
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+	return lng - 180
+}
+
+// Haversine returns the great circle distance in meters between a and b on a spherical earth
+func Haversine(a, b Point) float64 {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	dLat := lat2 - lat1
+	dLng := toRadians(b.Lng - a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+// Vincenty returns the distance in meters between a and b on the WGS84 ellipsoid. It is accurate
+// to millimetres but fails to converge for nearly antipodal points
+func Vincenty(a, b Point) (float64, error) {
+	L := toRadians(b.Lng - a.Lng)
+	U1 := math.Atan((1 - wgs84F) * math.Tan(toRadians(a.Lat)))
+	U2 := math.Atan((1 - wgs84F) * math.Tan(toRadians(b.Lat)))
+	sinU1, cosU1 := math.Sin(U1), math.Cos(U1)
+	sinU2, cosU2 := math.Sin(U2), math.Cos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	for i := 0; ; i++ {
+		if i == 200 {
+			return 0, ErrNoConvergence
+		}
+		sinLambda, cosLambda := math.Sin(lambda), math.Cos(lambda)
+		sinSigma = math.Sqrt((cosU2*sinLambda)*(cosU2*sinLambda) + (cosU1*sinU2-sinU1*cosU2*cosLambda)*(cosU1*sinU2-sinU1*cosU2*cosLambda))
+		if sinSigma == 0 {
+			return 0, nil // coincident points
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha // zero on the equator
+		}
+		C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			break
+		}
+	}
+	uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+	return wgs84B * A * (sigma - deltaSigma), nil
+}
+
+// InitialBearing returns the bearing in degrees clockwise from north, in [0, 360),
+// to set off on from a to reach b along a great circle
+func InitialBearing(a, b Point) float64 {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	dLng := toRadians(b.Lng - a.Lng)
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360)
+}
+
+// FinalBearing returns the bearing in degrees on arrival at b when travelling from a along a great circle
+func FinalBearing(a, b Point) float64 {
+	return math.Mod(InitialBearing(b, a)+180, 360)
+}
+
+// Destination returns the point reached travelling distance meters from p on the given bearing
+func Destination(p Point, bearing, distance float64) Point {
+	delta := distance / EarthRadius
+	theta := toRadians(bearing)
+	lat1, lng1 := toRadians(p.Lat), toRadians(p.Lng)
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	lng2 := lng1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+	return Point{Lat: toDegrees(lat2), Lng: NormalizeLng(toDegrees(lng2))}
+}
+
+// Midpoint returns the point halfway between a and b along a great circle
+func Midpoint(a, b Point) Point {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	lng1 := toRadians(a.Lng)
+	dLng := toRadians(b.Lng - a.Lng)
+	bx := math.Cos(lat2) * math.Cos(dLng)
+	by := math.Cos(lat2) * math.Sin(dLng)
+	lat := math.Atan2(math.Sin(lat1)+math.Sin(lat2), math.Sqrt((math.Cos(lat1)+bx)*(math.Cos(lat1)+bx)+by*by))
+	lng := lng1 + math.Atan2(by, math.Cos(lat1)+bx)
+	return Point{Lat: toDegrees(lat), Lng: NormalizeLng(toDegrees(lng))}
+}
+
+// Interpolate returns the point a fraction of the way from a to b along a great circle.
+// A fraction of 0 is a and 1 is b
+func Interpolate(a, b Point, fraction float64) Point {
+	delta := Haversine(a, b) / EarthRadius
+	if delta == 0 {
+		return a
+	}
+	lat1, lng1 := toRadians(a.Lat), toRadians(a.Lng)
+	lat2, lng2 := toRadians(b.Lat), toRadians(b.Lng)
+	wa := math.Sin((1-fraction)*delta) / math.Sin(delta)
+	wb := math.Sin(fraction*delta) / math.Sin(delta)
+	x := wa*math.Cos(lat1)*math.Cos(lng1) + wb*math.Cos(lat2)*math.Cos(lng2)
+	y := wa*math.Cos(lat1)*math.Sin(lng1) + wb*math.Cos(lat2)*math.Sin(lng2)
+	z := wa*math.Sin(lat1) + wb*math.Sin(lat2)
+	return Point{Lat: toDegrees(math.Atan2(z, math.Sqrt(x*x+y*y))), Lng: NormalizeLng(toDegrees(math.Atan2(y, x)))}
+}
+
+// DistanceToSegment returns the distance in meters from p to the closest point on the great circle
+// segment from a to b. When p lies beyond either end this is the distance to that end
+func DistanceToSegment(p, a, b Point) float64 {
+	d12 := Haversine(a, b) / EarthRadius
+	d13 := Haversine(a, p) / EarthRadius
+	if d12 == 0 || d13 == 0 {
+		return d13 * EarthRadius
+	}
+	dTheta := toRadians(InitialBearing(a, p) - InitialBearing(a, b))
+	if math.Cos(dTheta) < 0 {
+		return d13 * EarthRadius // p is behind a
+	}
+	crossTrack := math.Asin(math.Sin(d13) * math.Sin(dTheta))
+	alongTrack := math.Acos(math.Max(-1, math.Min(1, math.Cos(d13)/math.Cos(crossTrack))))
+	if alongTrack > d12 {
+		return Haversine(b, p) // p is beyond b
+	}
+	return math.Abs(crossTrack) * EarthRadius
+}
@@ -0,0 +1,159 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Random points away from the poles, where bearings are undefined
+type testPoint Point
+
+func (testPoint) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(testPoint{Lat: r.Float64()*170 - 85, Lng: r.Float64()*360 - 180})
+}
+
+// Random distances up to 2000km in meters
+type testDistance float64
+
+func (testDistance) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(testDistance(r.Float64() * 2e6))
+}
+
+// Random bearings in degrees
+type testBearing float64
+
+func (testBearing) Generate(r *rand.Rand, _ int) reflect.Value {
+	return reflect.ValueOf(testBearing(r.Float64() * 360))
+}
+
+var quickConfig = &quick.Config{MaxCount: 500}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// Testing distances on known city pairs
+func Test_Distances(t *testing.T) {
+	halifax := Point{Lat: 44.6488, Lng: -63.5752}
+	boston := Point{Lat: 42.3601, Lng: -71.0589}
+	assert.InDelta(t, 655e3, Haversine(halifax, boston), 5e3)
+	d, err := Vincenty(halifax, boston)
+	assert.NoError(t, err)
+	assert.InDelta(t, Haversine(halifax, boston), d, d*0.005)
+
+	_, err = Vincenty(Point{Lat: 0, Lng: 0}, Point{Lat: 0.5, Lng: 179.7})
+	assert.ErrorIs(t, err, ErrNoConvergence)
+	d, err = Vincenty(halifax, halifax)
+	assert.NoError(t, err)
+	assert.Zero(t, d)
+}
+
+// Testing Haversine is symmetric, non negative and bounded by half the circumference
+func Test_Haversine_Properties(t *testing.T) {
+	f := func(a, b testPoint) bool {
+		d := Haversine(Point(a), Point(b))
+		return d >= 0 && d <= math.Pi*EarthRadius+1e-6 && near(d, Haversine(Point(b), Point(a)), 1e-6)
+	}
+	assert.NoError(t, quick.Check(f, quickConfig))
+}
+
+// Testing Vincenty agrees with Haversine within the error of the spherical model
+func Test_Vincenty_Properties(t *testing.T) {
+	f := func(a testPoint, bearing testBearing, distance testDistance) bool {
+		b := Destination(Point(a), float64(bearing), float64(distance))
+		d, err := Vincenty(Point(a), b)
+		return err == nil && near(d, float64(distance), float64(distance)*0.006+1e-3)
+	}
+	assert.NoError(t, quick.Check(f, quickConfig))
+}
+
+// Testing travelling to a destination covers the distance on the initial bearing
+func Test_Destination_Properties(t *testing.T) {
+	f := func(a testPoint, bearing testBearing, distance testDistance) bool {
+		b := Destination(Point(a), float64(bearing), float64(distance))
+		if !near(Haversine(Point(a), b), float64(distance), 1e-3) {
+			return false
+		}
+		if distance < 1 {
+			return true
+		}
+		diff := math.Abs(InitialBearing(Point(a), b) - float64(bearing))
+		return math.Min(diff, 360-diff) < 1e-6
+	}
+	assert.NoError(t, quick.Check(f, quickConfig))
+}
+
+// Testing the midpoint is equidistant from both ends and final bearing matches the reverse trip
+func Test_Midpoint_Properties(t *testing.T) {
+	f := func(a testPoint, bearing testBearing, distance testDistance) bool {
+		b := Destination(Point(a), float64(bearing), float64(distance))
+		m := Midpoint(Point(a), b)
+		half := Haversine(Point(a), b) / 2
+		reverse := math.Mod(InitialBearing(b, Point(a))+180, 360)
+		diff := math.Abs(FinalBearing(Point(a), b) - reverse)
+		return near(Haversine(Point(a), m), half, 1e-3) && near(Haversine(b, m), half, 1e-3) && math.Min(diff, 360-diff) < 1e-6
+	}
+	assert.NoError(t, quick.Check(f, quickConfig))
+}
+
+// Testing bounds around a circle contain every point on the circle and intersect it
+func Test_BoundsAround_Properties(t *testing.T) {
+	f := func(center testPoint, bearing testBearing, distance testDistance) bool {
+		b := BoundsAround(Point(center), float64(distance))
+		edge := Destination(Point(center), float64(bearing), float64(distance)*0.999)
+		return b.Contains(Point(center)) && b.Contains(edge) && b.Intersects(b) &&
+			CircleIntersectsBounds(Point(center), float64(distance), b)
+	}
+	assert.NoError(t, quick.Check(f, quickConfig))
+}
+
+// Testing boxes crossing the antimeridian
+func Test_Bounds_Antimeridian(t *testing.T) {
+	fiji := BoundsAround(Point{Lat: -17.7, Lng: 179.9}, 50e3)
+	assert.True(t, fiji.CrossesAntimeridian())
+	assert.True(t, fiji.Contains(Point{Lat: -17.7, Lng: -179.9}))
+	assert.True(t, fiji.Contains(Point{Lat: -17.7, Lng: 179.7}))
+	assert.False(t, fiji.Contains(Point{Lat: -17.7, Lng: 0}))
+	assert.InDelta(t, 179.9, fiji.Center().Lng, 1e-9)
+	assert.Len(t, fiji.Split(), 2)
+	assert.InDelta(t, fiji.LngSpan(), fiji.Split()[0].LngSpan()+fiji.Split()[1].LngSpan(), 1e-9)
+
+	east := Bounds{SW: Point{Lat: -18, Lng: -179.95}, NE: Point{Lat: -17, Lng: -179}}
+	assert.True(t, fiji.Intersects(east))
+	assert.False(t, fiji.Intersects(Bounds{SW: Point{Lat: -18, Lng: 10}, NE: Point{Lat: -17, Lng: 11}}))
+
+	b := Bounds{SW: Point{Lat: 0, Lng: 170}, NE: Point{Lat: 1, Lng: 175}}.Extend(Point{Lat: 2, Lng: -178})
+	assert.True(t, b.CrossesAntimeridian())
+	assert.Equal(t, 2.0, b.NE.Lat)
+	assert.True(t, CircleIntersectsBounds(Point{Lat: 0.5, Lng: -179.99}, 2e3, Bounds{SW: Point{Lat: 0, Lng: 170}, NE: Point{Lat: 1, Lng: 180}}))
+	assert.Equal(t, -180.0, NormalizeLng(180))
+	assert.Equal(t, 170.0, NormalizeLng(-190))
+}
+
+// Testing circles reaching a pole span every longitude
+func Test_BoundsAround_Pole(t *testing.T) {
+	b := BoundsAround(Point{Lat: 89.9, Lng: 10}, 50e3)
+	assert.Equal(t, 90.0, b.NE.Lat)
+	assert.Equal(t, 360.0, b.LngSpan())
+}
+
+// Testing interpolation along a great circle and distance to a segment
+func Test_Interpolate_DistanceToSegment(t *testing.T) {
+	a, b := Point{Lat: 0, Lng: 0}, Point{Lat: 0, Lng: 10}
+	assert.Equal(t, a, Interpolate(a, b, 0))
+	assert.InDelta(t, 10.0, Interpolate(a, b, 1).Lng, 1e-9)
+	mid := Midpoint(Point{Lat: 40, Lng: -74}, Point{Lat: 51.5, Lng: -0.1})
+	half := Interpolate(Point{Lat: 40, Lng: -74}, Point{Lat: 51.5, Lng: -0.1}, 0.5)
+	assert.InDelta(t, 0, Haversine(mid, half), 1e-3)
+	assert.Equal(t, a, Interpolate(a, a, 0.5))
+
+	assert.InDelta(t, Haversine(Point{Lat: 1, Lng: 5}, Point{Lat: 0, Lng: 5}), DistanceToSegment(Point{Lat: 1, Lng: 5}, a, b), 1)
+	assert.InDelta(t, Haversine(Point{Lat: 0, Lng: -1}, a), DistanceToSegment(Point{Lat: 0, Lng: -1}, a, b), 1e-6)
+	assert.InDelta(t, Haversine(Point{Lat: 1, Lng: 11}, b), DistanceToSegment(Point{Lat: 1, Lng: 11}, a, b), 1e-6)
+	assert.InDelta(t, Haversine(Point{Lat: 1, Lng: 1}, a), DistanceToSegment(Point{Lat: 1, Lng: 1}, a, a), 1e-6)
+}
@@ -0,0 +1,336 @@
+package geo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoOpeningHours is returned when a place does not report any opening hours
+var ErrNoOpeningHours = errors.New("maps: place has no opening hours")
+
+// How far ahead NextOpen and NextClose look. Longer than a week so every weekly period is seen
+const scheduleHorizon = 15 * 24 * time.Hour
+
+// Schedule answers questions about when a place is open. It works on the weekly
+// periods Google reports, evaluated as wall clock times in the place's time zone,
+// so overnight periods and daylight saving changes are handled.
+//
+// Periods with a Date (as in currentOpeningHours) apply to that date only. On a SpecialDay
+// the weekly periods are ignored and only dated periods apply, which is how holiday
+// closures are reported. A period without a close point means the place never closes.
+type Schedule struct {
+	hours OpeningHours
+	loc   *time.Location
+}
+
+// An interval the place is open for, start inclusive, end exclusive
+type openInterval struct {
+	start, end time.Time
+}
+
+// NewSchedule evaluates hours in the time zone loc. A nil loc means UTC
+func NewSchedule(hours OpeningHours, loc *time.Location) *Schedule {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Schedule{hours: hours, loc: loc}
+}
+
+// PlaceSchedule builds a Schedule for a place. Regular weekly hours are combined with the
+// exceptions in current opening hours. When secondary is set the matching secondary hours
+// (eg. DRIVE_THROUGH) are used instead.
+func PlaceSchedule(p Place, secondary SecondaryHoursType) (*Schedule, error) {
+	hours := p.RegularOpeningHours
+	if secondary != "" {
+		found := false
+		for _, h := range p.RegularSecondaryOpeningHours {
+			if h.SecondaryHoursType == secondary {
+				hours, found = h, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("maps: place has no %s hours", secondary)
+		}
+	} else if len(p.CurrentOpeningHours.Periods) > 0 {
+		hours.Periods = append(append([]Period{}, hours.Periods...), datedPeriods(p.CurrentOpeningHours.Periods)...)
+		hours.SpecialDays = p.CurrentOpeningHours.SpecialDays
+	}
+	if len(hours.Periods) == 0 {
+		return nil, ErrNoOpeningHours
+	}
+	return NewSchedule(hours, PlaceLocation(p)), nil
+}
+
+// PlaceLocation returns the time zone of a place. It prefers the IANA zone, falling back to
+// the fixed UTC offset and finally UTC when neither was requested in the field mask.
+func PlaceLocation(p Place) *time.Location {
+	if p.Timezone.Id != "" {
+		if loc, err := time.LoadLocation(p.Timezone.Id); err == nil {
+			return loc
+		}
+	}
+	if p.UtcOffsetMinutes != nil {
+		return time.FixedZone("", int(*p.UtcOffsetMinutes)*60)
+	}
+	return time.UTC
+}
+
+// OpenAt reports whether the place is open at t
+func (s *Schedule) OpenAt(t time.Time) bool {
+	for _, i := range s.intervals(t, t) {
+		if !t.Before(i.start) && t.Before(i.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenDuring reports whether the place stays open for the whole of [from, to]
+func (s *Schedule) OpenDuring(from, to time.Time) bool {
+	if to.Before(from) {
+		from, to = to, from
+	}
+	for _, i := range s.intervals(from, to) {
+		if !from.Before(i.start) && !to.After(i.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the next time after t the place opens. It returns false when the place is
+// always open or does not open again within two weeks.
+func (s *Schedule) NextOpen(t time.Time) (time.Time, bool) {
+	for _, i := range s.intervals(t, t.Add(scheduleHorizon)) {
+		if i.start.After(t) {
+			return i.start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextClose returns the next time after t the place closes. If the place is open at t this
+// is the end of the current opening. It returns false when the place never closes.
+func (s *Schedule) NextClose(t time.Time) (time.Time, bool) {
+	for _, i := range s.intervals(t, t.Add(scheduleHorizon)) {
+		if i.end.After(t) && !i.end.After(t.Add(scheduleHorizon)) {
+			return i.end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Concrete opening intervals overlapping [from, to], sorted and merged so that periods
+// that touch (a truncated point, or closing at midnight and reopening at midnight) form one interval
+func (s *Schedule) intervals(from, to time.Time) []openInterval {
+	from, to = from.In(s.loc), to.In(s.loc)
+	// Weekly periods are recurred from a week before from, so an overnight period that started
+	// in the previous week is included, until a week after to
+	first := weekStart(from).AddDate(0, 0, -7)
+	last := weekStart(to).AddDate(0, 0, 7)
+	special := map[Date]bool{}
+	for _, d := range s.hours.SpecialDays {
+		special[d.Date] = true
+	}
+	var intervals []openInterval
+	for _, p := range s.hours.Periods {
+		if p.Close == nil {
+			// Open around the clock. Cover the whole range searched
+			intervals = append(intervals, openInterval{start: first, end: last.AddDate(0, 0, 7)})
+			continue
+		}
+		if p.Open.Date.Year != 0 {
+			intervals = append(intervals, s.datedInterval(p))
+			continue
+		}
+		for week := first; !week.After(last); week = week.AddDate(0, 0, 7) {
+			y, m, d := week.Date()
+			start := time.Date(y, m, d+p.Open.Day, p.Open.Hour, p.Open.Minute, 0, 0, s.loc)
+			if special[dateOf(start)] {
+				continue
+			}
+			end := time.Date(y, m, d+p.Close.Day, p.Close.Hour, p.Close.Minute, 0, 0, s.loc)
+			if !end.After(start) {
+				// Overnight period closing in the following week. Eg: Saturday 22:00 to Sunday 02:00
+				end = time.Date(y, m, d+p.Close.Day+7, p.Close.Hour, p.Close.Minute, 0, 0, s.loc)
+			}
+			intervals = append(intervals, openInterval{start: start, end: end})
+		}
+	}
+	return mergeIntervals(intervals)
+}
+
+// Interval for a period tied to a specific date
+func (s *Schedule) datedInterval(p Period) openInterval {
+	o, c := p.Open, *p.Close
+	start := time.Date(o.Date.Year, time.Month(o.Date.Month), o.Date.Day, o.Hour, o.Minute, 0, 0, s.loc)
+	var end time.Time
+	if c.Date.Year != 0 {
+		end = time.Date(c.Date.Year, time.Month(c.Date.Month), c.Date.Day, c.Hour, c.Minute, 0, 0, s.loc)
+	} else {
+		days := (c.Day - o.Day + 7) % 7
+		end = time.Date(o.Date.Year, time.Month(o.Date.Month), o.Date.Day+days, c.Hour, c.Minute, 0, 0, s.loc)
+	}
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 7)
+	}
+	return openInterval{start: start, end: end}
+}
+
+// Periods from current opening hours that are tied to a date
+func datedPeriods(periods []Period) []Period {
+	var dated []Period
+	for _, p := range periods {
+		if p.Open.Date.Year != 0 {
+			dated = append(dated, p)
+		}
+	}
+	return dated
+}
+
+func mergeIntervals(intervals []openInterval) []openInterval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+	var merged []openInterval
+	for _, i := range intervals {
+		if n := len(merged); n > 0 && !i.start.After(merged[n-1].end) {
+			if i.end.After(merged[n-1].end) {
+				merged[n-1].end = i.end
+			}
+			continue
+		}
+		merged = append(merged, i)
+	}
+	return merged
+}
+
+// Midnight at the start of the Sunday on or before t, in t's location. Google numbers days from Sunday = 0
+func weekStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d-int(t.Weekday()), 0, 0, 0, 0, t.Location())
+}
+
+func dateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{Year: y, Month: int(m), Day: d}
+}
+
+// OpenStatus is the result of checking a place against a time window
+type OpenStatus string
+
+const (
+	OpenStatusOpen    = OpenStatus("OPEN")
+	OpenStatusClosed  = OpenStatus("CLOSED")
+	OpenStatusUnknown = OpenStatus("UNKNOWN") // The place reports no opening hours
+)
+
+// Field masks needed to evaluate opening hours in the place's own time zone
+var OpeningHoursFieldMasks = []PlaceFieldMask{PlaceFieldMaskOpeningHours, PlaceFieldMaskCurrentOpeningHours, PlaceFieldMaskTimezone, PlaceFieldMaskUtcOffset}
+
+// PlaceOpenDuring reports whether p is open for the whole of [from, to], evaluated in the place's time zone.
+// A zero to checks whether the place is open at from.
+func PlaceOpenDuring(p Place, from, to time.Time) OpenStatus {
+	schedule, err := PlaceSchedule(p, "")
+	if err != nil {
+		return OpenStatusUnknown
+	}
+	open := schedule.OpenAt(from)
+	if !to.IsZero() {
+		open = schedule.OpenDuring(from, to)
+	}
+	if open {
+		return OpenStatusOpen
+	}
+	return OpenStatusClosed
+}
+
+// FilterOpenDuring returns the places open for the whole of [from, to]. Places without opening hours are dropped
+func FilterOpenDuring(places []Place, from, to time.Time) []Place {
+	var open []Place
+	for _, p := range places {
+		if PlaceOpenDuring(p, from, to) == OpenStatusOpen {
+			open = append(open, p)
+		}
+	}
+	return open
+}
+
+// Page token for a page of open places that was cut short. Google's tokens only point at whole pages,
+// so the token that fetched the page is kept with the number of its open places already returned
+type openPageCursor struct {
+	PageToken string `json:"pageToken,omitempty"` // Empty for the first page
+	Skip      int    `json:"skip"`
+}
+
+const openPageCursorPrefix = "open:"
+
+func (c openPageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return openPageCursorPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeOpenPageCursor(token string) (openPageCursor, bool) {
+	encoded, ok := strings.CutPrefix(token, openPageCursorPrefix)
+	if !ok {
+		return openPageCursor{}, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	var c openPageCursor
+	if err != nil || json.Unmarshal(data, &c) != nil || c.Skip < 0 {
+		return openPageCursor{}, false
+	}
+	return c, true
+}
+
+// TextSearchOpenDuring runs a text search keeping only places open for the whole of [from, to].
+// As filtering shrinks pages, follow up pages are fetched until PageSize open places were
+// found, the results run out or maxPages pages were read. At most PageSize places are returned.
+// When a page is cut short, NextPageToken points back into it, so passing it with the same
+// request and window resumes exactly after the last place returned.
+func (c *GeoClient) TextSearchOpenDuring(ctx context.Context, r *TextSearchRequest, h *PlacesHeader, from, to time.Time, maxPages int) (PlacesSearchResponse, error) {
+	req := *r
+	header := PlacesHeader{FieldMaskPrefix: true}
+	if h != nil {
+		header = *h
+	}
+	for _, mask := range OpeningHoursFieldMasks {
+		header.FieldMasks = withFieldMask(header.FieldMasks, mask)
+	}
+	header.TokenMask = MaskNextPageToken
+	want := int(req.PageSize)
+	if want <= 0 {
+		want = maxNearbyResults
+	}
+	skip := 0
+	if cursor, ok := decodeOpenPageCursor(req.PageToken); ok {
+		req.PageToken, skip = cursor.PageToken, cursor.Skip
+	}
+	var resp PlacesSearchResponse
+	for pages := 0; maxPages <= 0 || pages < maxPages; pages++ {
+		page, err := c.textSearchPage(ctx, &req, &header)
+		if err != nil {
+			return PlacesSearchResponse{}, err
+		}
+		open := FilterOpenDuring(page.Places, from, to)
+		offset := min(skip, len(open))
+		open, skip = open[offset:], 0
+		if room := want - len(resp.Places); len(open) > room {
+			resp.Places = append(resp.Places, open[:room]...)
+			resp.NextPageToken = openPageCursor{PageToken: req.PageToken, Skip: offset + room}.encode()
+			break
+		}
+		resp.Places = append(resp.Places, open...)
+		resp.NextPageToken = page.NextPageToken
+		if page.NextPageToken == "" || len(resp.Places) >= want {
+			break
+		}
+		req.PageToken = page.NextPageToken
+	}
+	return resp, nil
+}
@@ -0,0 +1,161 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // Halifax zone data for DST cases, independent of the host
+
+	"github.com/geolocate/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func weekly(openDay, openHour, closeDay, closeHour int) Period {
+	return Period{Open: Point{Day: openDay, Hour: openHour}, Close: &Point{Day: closeDay, Hour: closeHour}}
+}
+
+func dated(year, month, day, openHour, closeHour int) Period {
+	date := Date{Year: year, Month: month, Day: day}
+	weekday := int(time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Weekday())
+	return Period{Open: Point{Date: date, Day: weekday, Hour: openHour}, Close: &Point{Date: date, Day: weekday, Hour: closeHour}}
+}
+
+// Testing schedules answer open at, open during and next open/close questions
+func Test_Schedule(t *testing.T) {
+	halifax, err := time.LoadLocation("America/Halifax")
+	assert.NoError(t, err)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, halifax)
+	}
+	officeHours := OpeningHours{Periods: []Period{weekly(1, 9, 1, 17), weekly(2, 9, 2, 17), weekly(3, 9, 3, 17), weekly(4, 9, 4, 17), weekly(5, 9, 5, 17)}}
+	weekendBar := OpeningHours{Periods: []Period{weekly(5, 20, 6, 2), weekly(6, 20, 0, 2)}}
+	allDay := OpeningHours{Periods: []Period{{Open: Point{Day: 0}}}}
+	dayByDay := OpeningHours{Periods: []Period{weekly(0, 0, 1, 0), weekly(1, 0, 2, 0), weekly(2, 0, 3, 0), weekly(3, 0, 4, 0), weekly(4, 0, 5, 0), weekly(5, 0, 6, 0), weekly(6, 0, 0, 0)}}
+	lateNight := OpeningHours{Periods: []Period{weekly(6, 22, 0, 4)}}
+	holidays := OpeningHours{
+		Periods:     append(append([]Period{}, officeHours.Periods...), dated(2026, 12, 24, 10, 14)),
+		SpecialDays: []SpecialDay{{Date: Date{Year: 2026, Month: 12, Day: 24}}, {Date: Date{Year: 2026, Month: 12, Day: 25}}},
+	}
+
+	tests := []struct {
+		name      string
+		hours     OpeningHours
+		from, to  time.Time
+		open      bool
+		during    bool
+		nextOpen  time.Time
+		nextClose time.Time
+	}{
+		{name: "weekday open", hours: officeHours, from: at(10, 21, 10, 0), to: at(10, 21, 16, 0), open: true, during: true, nextOpen: at(10, 22, 9, 0), nextClose: at(10, 21, 17, 0)},
+		{name: "weekday evening", hours: officeHours, from: at(10, 21, 18, 0), to: at(10, 21, 19, 0), nextOpen: at(10, 22, 9, 0), nextClose: at(10, 22, 17, 0)},
+		{name: "window past closing", hours: officeHours, from: at(10, 21, 16, 0), to: at(10, 21, 17, 30), open: true, nextOpen: at(10, 22, 9, 0), nextClose: at(10, 21, 17, 0)},
+		{name: "weekend", hours: officeHours, from: at(10, 24, 12, 0), to: at(10, 24, 13, 0), nextOpen: at(10, 26, 9, 0), nextClose: at(10, 26, 17, 0)},
+		{name: "overnight after midnight", hours: weekendBar, from: at(10, 24, 1, 0), to: at(10, 24, 1, 59), open: true, during: true, nextOpen: at(10, 24, 20, 0), nextClose: at(10, 24, 2, 0)},
+		{name: "overnight across week boundary", hours: weekendBar, from: at(10, 24, 23, 0), to: at(10, 25, 1, 30), open: true, during: true, nextOpen: at(10, 30, 20, 0), nextClose: at(10, 25, 2, 0)},
+		{name: "always open", hours: allDay, from: at(10, 21, 3, 0), to: at(11, 2, 3, 0), open: true, during: true},
+		{name: "open day by day", hours: dayByDay, from: at(10, 23, 22, 0), to: at(10, 26, 2, 0), open: true, during: true},
+		{name: "daylight saving starts overnight", hours: lateNight, from: at(3, 7, 23, 0), to: at(3, 8, 3, 30), open: true, during: true, nextOpen: at(3, 14, 22, 0), nextClose: at(3, 8, 4, 0)},
+		{name: "holiday short hours", hours: holidays, from: at(12, 24, 11, 0), to: at(12, 24, 15, 0), open: true, nextOpen: at(12, 28, 9, 0), nextClose: at(12, 24, 14, 0)},
+		{name: "holiday closed", hours: holidays, from: at(12, 25, 10, 0), to: at(12, 25, 11, 0), nextOpen: at(12, 28, 9, 0), nextClose: at(12, 28, 17, 0)},
+	}
+	for _, tt := range tests {
+		s := NewSchedule(tt.hours, halifax)
+		assert.Equal(t, tt.open, s.OpenAt(tt.from), tt.name)
+		assert.Equal(t, tt.during, s.OpenDuring(tt.from, tt.to), tt.name)
+		nextOpen, ok := s.NextOpen(tt.from)
+		assert.Equal(t, !tt.nextOpen.IsZero(), ok, tt.name)
+		assert.True(t, tt.nextOpen.Equal(nextOpen), "%s: next open %s", tt.name, nextOpen)
+		nextClose, ok := s.NextClose(tt.from)
+		assert.Equal(t, !tt.nextClose.IsZero(), ok, tt.name)
+		assert.True(t, tt.nextClose.Equal(nextClose), "%s: next close %s", tt.name, nextClose)
+	}
+}
+
+// Testing the late night period lasts one hour less when clocks go forward
+func Test_Schedule_DST(t *testing.T) {
+	halifax, err := time.LoadLocation("America/Halifax")
+	assert.NoError(t, err)
+	s := NewSchedule(OpeningHours{Periods: []Period{weekly(6, 22, 0, 4)}}, halifax)
+	open := time.Date(2026, 3, 7, 22, 0, 0, 0, halifax)
+	close, ok := s.NextClose(open)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Hour, close.Sub(open))
+}
+
+// Testing place schedules pick secondary hours, current hours and time zone
+func Test_PlaceSchedule(t *testing.T) {
+	offset := int32(-180)
+	place := Place{
+		UtcOffsetMinutes:    &offset,
+		RegularOpeningHours: OpeningHours{Periods: []Period{weekly(1, 9, 1, 17)}},
+		RegularSecondaryOpeningHours: []OpeningHours{
+			{SecondaryHoursType: SecondaryHoursTypeDriveThrough, Periods: []Period{weekly(1, 6, 1, 23)}},
+		},
+	}
+	monday := time.Date(2026, 10, 19, 20, 0, 0, 0, time.FixedZone("", -180*60))
+	s, err := PlaceSchedule(place, "")
+	assert.NoError(t, err)
+	assert.False(t, s.OpenAt(monday))
+	s, err = PlaceSchedule(place, SecondaryHoursTypeDriveThrough)
+	assert.NoError(t, err)
+	assert.True(t, s.OpenAt(monday))
+	assert.True(t, s.OpenAt(monday.UTC())) // same instant in another zone
+	_, err = PlaceSchedule(place, SecondaryHoursTypeHappyHour)
+	assert.Error(t, err)
+	_, err = PlaceSchedule(Place{}, "")
+	assert.ErrorIs(t, err, ErrNoOpeningHours)
+
+	place.Timezone = Timezone{Id: "America/Halifax"}
+	assert.Equal(t, "America/Halifax", PlaceLocation(place).String())
+	place.Timezone = Timezone{}
+	place.UtcOffsetMinutes = nil
+	assert.Equal(t, time.UTC, PlaceLocation(place))
+}
+
+// Testing closed places are dropped and further pages fetched to refill the page
+func Test_TextSearchOpenDuring(t *testing.T) {
+	noPageTokenDelay(t)
+	lateHours := OpeningHours{Periods: []Period{weekly(5, 20, 6, 2)}}
+	dayHours := OpeningHours{Periods: []Period{weekly(5, 9, 5, 17)}}
+	utc := Timezone{Id: "UTC"}
+	pages := map[string]PlacesSearchResponse{
+		"":      {Places: []Place{{Id: "cafe", RegularOpeningHours: dayHours, Timezone: utc}, {Id: "bar1", RegularOpeningHours: lateHours, Timezone: utc}}, NextPageToken: "page2"},
+		"page2": {Places: []Place{{Id: "bar2", RegularOpeningHours: lateHours, Timezone: utc}, {Id: "unknown"}, {Id: "bar3", RegularOpeningHours: lateHours, Timezone: utc}}, NextPageToken: "page3"},
+		"page3": {Places: []Place{{Id: "bar4", RegularOpeningHours: lateHours, Timezone: utc}}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.Contains(r.Header.Get("X-Goog-FieldMask"), "places.timeZone"))
+		var req TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(pages[req.PageToken])
+	}))
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	friday := time.Date(2026, 10, 23, 23, 0, 0, 0, time.UTC)
+	req := TextSearchRequest{TextQuery: "bars", PageSize: 3}
+	resp, err := testGeoClient.TextSearchOpenDuring(context.Background(), &req, nil, friday, friday.Add(2*time.Hour), 0)
+	assert.NoError(t, err)
+	var ids []string
+	for _, p := range resp.Places {
+		ids = append(ids, p.Id)
+	}
+	assert.Equal(t, []string{"bar1", "bar2", "bar3"}, ids)
+	assert.Equal(t, "page3", resp.NextPageToken)
+
+	// A page cut short is resumed from the place after the last one returned
+	req = TextSearchRequest{TextQuery: "bars", PageSize: 2}
+	resp, err = testGeoClient.TextSearchOpenDuring(context.Background(), &req, nil, friday, friday.Add(2*time.Hour), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar1", "bar2"}, resp.PlaceIDs())
+	req.PageToken = resp.NextPageToken
+	resp, err = testGeoClient.TextSearchOpenDuring(context.Background(), &req, nil, friday, friday.Add(2*time.Hour), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar3", "bar4"}, resp.PlaceIDs())
+	assert.Empty(t, resp.NextPageToken)
+}
@@ -0,0 +1,98 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/geolocate/client"
+)
+
+// A freshly issued page token is not usable straight away. Google rejects it with
+// HTTP 400 until it propagates, so we wait before every follow up page and retry a few times.
+var (
+	pageTokenDelay   = 2 * time.Second
+	pageTokenRetries = 3
+)
+
+// TextSearchAll returns an iterator over every Place matching a TextSearchRequest. It follows
+// nextPageToken transparently until the results run out, maxResults places were yielded
+// (0 means no cap) or ctx is cancelled. nextPageToken is added to the field mask automatically.
+// Iteration stops after the first error is yielded.
+//
+//	for place, err := range apiClient.TextSearchAll(ctx, &req, &header, 50) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (c *GeoClient) TextSearchAll(ctx context.Context, r *TextSearchRequest, h *PlacesHeader, maxResults int) iter.Seq2[Place, error] {
+	return func(yield func(Place, error) bool) {
+		req := *r // Never mutate callers request. Only the page token changes between pages
+		header := PlacesHeader{FieldMaskPrefix: true}
+		if h != nil {
+			header = *h
+		}
+		header.TokenMask = MaskNextPageToken
+		count := 0
+		for {
+			page, err := c.textSearchPage(ctx, &req, &header)
+			if err != nil {
+				yield(Place{}, err)
+				return
+			}
+			for _, place := range page.Places {
+				if !yield(place, nil) {
+					return
+				}
+				count++
+				if maxResults > 0 && count >= maxResults {
+					return
+				}
+			}
+			if page.NextPageToken == "" {
+				return
+			}
+			req.PageToken = page.NextPageToken
+		}
+	}
+}
+
+// Fetch a single page. Follow up pages wait for the token to warm up and are retried
+// while Google still reports the token as invalid
+func (c *GeoClient) textSearchPage(ctx context.Context, r *TextSearchRequest, h *PlacesHeader) (PlacesSearchResponse, error) {
+	if r.PageToken == "" {
+		return c.TextSearch(ctx, r, h)
+	}
+	var err error
+	for attempt := 0; attempt < pageTokenRetries; attempt++ {
+		if err := sleepContext(ctx, pageTokenDelay); err != nil {
+			return PlacesSearchResponse{}, err
+		}
+		var page PlacesSearchResponse
+		page, err = c.TextSearch(ctx, r, h)
+		var httpErr client.HttpError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusBadRequest {
+			continue
+		}
+		return page, err
+	}
+	return PlacesSearchResponse{}, err
+}
+
+// Wait for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
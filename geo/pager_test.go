@@ -0,0 +1,102 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/geolocate/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// Fake Places text search serving three pages of two places. The token for the second page
+// is rejected once to mimic Google's warm up delay
+func newPagedTextSearchServer(t *testing.T) (*httptest.Server, *int) {
+	calls := 0
+	rejected := false
+	pages := map[string]PlacesSearchResponse{
+		"":      {Places: []Place{{Id: "1"}, {Id: "2"}}, NextPageToken: "page2"},
+		"page2": {Places: []Place{{Id: "3"}, {Id: "4"}}, NextPageToken: "page3"},
+		"page3": {Places: []Place{{Id: "5"}}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.True(t, strings.Contains(r.Header.Get("X-Goog-FieldMask"), MaskNextPageToken))
+		var req TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "bowling arena", req.TextQuery)
+		if req.PageToken == "page2" && !rejected {
+			rejected = true
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(pages[req.PageToken])
+	}))
+	return srv, &calls
+}
+
+// Skip the wait before follow up pages for the rest of the test
+func noPageTokenDelay(t *testing.T) {
+	delay := pageTokenDelay
+	pageTokenDelay = 0
+	t.Cleanup(func() { pageTokenDelay = delay })
+}
+
+// Testing TextSearchAll follows page tokens until results run out
+func Test_TextSearchAll(t *testing.T) {
+	noPageTokenDelay(t)
+	srv, calls := newPagedTextSearchServer(t)
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	req := TextSearchRequest{TextQuery: "bowling arena", PageSize: 2}
+	var ids []string
+	for place, err := range testGeoClient.TextSearchAll(context.Background(), &req, &PlacesHeader{FieldMaskPrefix: true}, 0) {
+		assert.NoError(t, err)
+		ids = append(ids, place.Id)
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, ids)
+	assert.Equal(t, 4, *calls) // includes the rejected warm up attempt
+	assert.Empty(t, req.PageToken)
+}
+
+// Testing TextSearchAll stops at maxResults without fetching further pages
+func Test_TextSearchAll_MaxResults(t *testing.T) {
+	noPageTokenDelay(t)
+	srv, calls := newPagedTextSearchServer(t)
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	req := TextSearchRequest{TextQuery: "bowling arena"}
+	var ids []string
+	for place, err := range testGeoClient.TextSearchAll(context.Background(), &req, nil, 2) {
+		assert.NoError(t, err)
+		ids = append(ids, place.Id)
+	}
+	assert.Equal(t, []string{"1", "2"}, ids)
+	assert.Equal(t, 1, *calls)
+}
+
+// Testing TextSearchAll yields the context error once cancelled
+func Test_TextSearchAll_Cancelled(t *testing.T) {
+	noPageTokenDelay(t)
+	srv, _ := newPagedTextSearchServer(t)
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := TextSearchRequest{TextQuery: "bowling arena"}
+	var lastErr error
+	for _, err := range testGeoClient.TextSearchAll(ctx, &req, nil, 0) {
+		cancel()
+		lastErr = err
+	}
+	assert.ErrorIs(t, lastErr, context.Canceled)
+}
@@ -3,7 +3,6 @@ package geo
 import (
 	"context"
 	"errors"
-	"os"
 	"strings"
 
 	// Included for image/jpeg's decoder
@@ -27,8 +26,7 @@ func (h *PlacesHeader) Headers() map[string]string {
 	if h.FieldMaskPrefix {
 		prefix = "places." // Only for Places(plural) requests. For looking up a single place, we dont need this prefix. This api is wierd
 	}
-	fieldMaskHeader := FieldMaskHeader(h.FieldMasks, prefix, h.TokenMask)
-	header["X-Goog-Api-Key"] = os.Getenv("API_KEY")
+	fieldMaskHeader := FieldMaskHeader(h.FieldMasks, prefix, h.TokenMask, h.ResponseMasks...)
 	header["X-Goog-FieldMask"] = strings.Join(fieldMaskHeader, ",")
 	header["Content-Type"] = "application/json"
 	return header
@@ -38,6 +36,8 @@ func (h *PlacesHeader) Headers() map[string]string {
 type PlacesSearchResponse struct {
 	Places        []Place `json:"places"`
 	NextPageToken string  `json:"nextPageToken"`
+	// RoutingSummaries are only returned when routing parameters are set. Entry i belongs to Places[i]
+	RoutingSummaries []RoutingSummary `json:"routingSummaries,omitempty"`
 }
 type LocalizedText struct {
 	Text         string `json:"text"`
@@ -50,13 +50,46 @@ type Place struct {
 	DisplayName         LocalizedText  `json:"displayName"`
 	Types               []string       `json:"types"`
 	FormattedAddress    string         `json:"formattedAddress"`
-	Rating              int32          `json:"rating"`
+	Rating              float64        `json:"rating"`
+	UserRatingCount     int32          `json:"userRatingCount,omitempty"`
 	Location            Location       `json:"location"`
 	BusinessStatus      BusinessStatus `json:"businessStatus"`
 	PhoneNumber         string         `json:"nationalPhoneNumber"`
 	Photos              []Photo        `json:"photos,omitempty"`
 	Timezone            Timezone       `json:"timeZone,omitempty"`
+	UtcOffsetMinutes    *int32         `json:"utcOffsetMinutes,omitempty"`
 	RegularOpeningHours OpeningHours   `json:"regularOpeningHours,omitempty"`
+	// Hours for the next seven days including exceptions such as holidays. Points carry a Date
+	CurrentOpeningHours          OpeningHours            `json:"currentOpeningHours,omitempty"`
+	RegularSecondaryOpeningHours []OpeningHours          `json:"regularSecondaryOpeningHours,omitempty"`
+	PlusCode                     *PlacePlusCode          `json:"plusCode,omitempty"`
+	PrimaryType                  string                  `json:"primaryType,omitempty"` // Eg: restaurant for a pizza place typed [pizza_restaurant, restaurant, food]
+	AddressComponents            []PlaceAddressComponent `json:"addressComponents,omitempty"`
+}
+
+// PlaceAddressComponent is a part of a place's address. The Places API equivalent of AddressComponent
+type PlaceAddressComponent struct {
+	LongText     string   `json:"longText"`
+	ShortText    string   `json:"shortText"`
+	Types        []string `json:"types"`
+	LanguageCode string   `json:"languageCode,omitempty"`
+}
+
+// PlaceIDs lists the IDs of the places found. Google allows caching these indefinitely
+func (r PlacesSearchResponse) PlaceIDs() []string {
+	ids := make([]string, 0, len(r.Places))
+	for _, p := range r.Places {
+		if p.Id != "" {
+			ids = append(ids, p.Id)
+		}
+	}
+	return ids
+}
+
+// PlacePlusCode is a place's plus code as returned by the Places API
+type PlacePlusCode struct {
+	GlobalCode   string `json:"globalCode"`
+	CompoundCode string `json:"compoundCode,omitempty"`
 }
 
 type NearbySearchRequest struct {
@@ -80,6 +113,39 @@ type TextSearchRequest struct {
 	LocationBias                     *LocationRestriction    `json:"locationBias,omitempty"`
 	RankPreference                   RankPreference          `json:"rankPreference,omitempty"`
 	LocationRestriction              *RectangularRestriction `json:"locationRestriction,omitempty"`
+	// Search along a route instead of around a location. Biases results to places near the polyline
+	SearchAlongRouteParameters *SearchAlongRouteParameters `json:"searchAlongRouteParameters,omitempty"`
+	// Routing parameters make Google return a RoutingSummary from origin to every place
+	RoutingParameters *RoutingParameters `json:"routingParameters,omitempty"`
+}
+
+// SearchAlongRouteParameters holds the route that a text search is carried out along
+type SearchAlongRouteParameters struct {
+	Polyline Polyline `json:"polyline"`
+}
+
+// Polyline is a route encoded with Google's encoded polyline algorithm, as returned by the Routes API
+type Polyline struct {
+	EncodedPolyline string `json:"encodedPolyline"`
+}
+
+// RoutingParameters configure how routing summaries to each place are calculated
+type RoutingParameters struct {
+	// Origin overrides the start of the route. Defaults to the first point of the polyline
+	Origin            *Location         `json:"origin,omitempty"`
+	TravelMode        TravelMode        `json:"travelMode,omitempty"`
+	RoutingPreference RoutingPreference `json:"routingPreference,omitempty"`
+}
+
+// RoutingSummary is the travel duration and distance to a place, split into legs.
+// Searching along a route gives two legs: origin to place and place back to the destination
+type RoutingSummary struct {
+	Legs          []RouteLeg `json:"legs"`
+	DirectionsUri string     `json:"directionsUri"`
+}
+type RouteLeg struct {
+	Duration       string `json:"duration"` // Seconds with an 's' suffix. Eg: "485s"
+	DistanceMeters int32  `json:"distanceMeters"`
 }
 
 // NearbySearch lets you search for places within a specified area. You can refine
@@ -105,6 +171,9 @@ func (c *GeoClient) TextSearch(ctx context.Context, r *TextSearchRequest, h *Pla
 	if r.TextQuery == "" && r.PageToken == "" {
 		return PlacesSearchResponse{}, errors.New("maps: Required fields Text Search and nextPage token are empty")
 	}
+	if r.SearchAlongRouteParameters != nil && r.SearchAlongRouteParameters.Polyline.EncodedPolyline == "" {
+		return PlacesSearchResponse{}, errors.New("maps: Required field encoded polyline missing for search along route")
+	}
 	var response PlacesSearchResponse
 	api := &client.ApiConfig{
 		Host: places.Host,
@@ -171,6 +240,7 @@ type PlacesHeader struct {
 	FieldMasks      []PlaceFieldMask
 	FieldMaskPrefix bool
 	TokenMask       string
+	ResponseMasks   []string // Response level fields such as routingSummaries. These are never prefixed
 }
 type StaticHeader struct {
 	ContentType string
@@ -178,7 +248,7 @@ type StaticHeader struct {
 	FieldMasks  []PlaceFieldMask
 }
 
-func FieldMaskHeader(placeFieldMasks []PlaceFieldMask, prefix string, tokenMask string) []string {
+func FieldMaskHeader(placeFieldMasks []PlaceFieldMask, prefix string, tokenMask string, responseMasks ...string) []string {
 	var fieldMask []string
 	for _, field := range placeFieldMasks {
 		fieldMask = append(fieldMask, string(prefix+string(field)))
@@ -186,6 +256,7 @@ func FieldMaskHeader(placeFieldMasks []PlaceFieldMask, prefix string, tokenMask
 	if tokenMask != "" {
 		fieldMask = append(fieldMask, tokenMask)
 	}
+	fieldMask = append(fieldMask, responseMasks...)
 	return fieldMask
 }
 
@@ -223,7 +294,33 @@ const (
 	PlaceFieldMaskPhotos               = PlaceFieldMask("photos")
 	PlaceFieldMaskPlaceID              = PlaceFieldMask("id")
 	PlaceFieldMaskRatings              = PlaceFieldMask("rating")
+	PlaceFieldMaskUserRatingCount      = PlaceFieldMask("userRatingCount")
+	PlaceFieldMaskLocation             = PlaceFieldMask("location")
 	PlaceFieldMaskTypes                = PlaceFieldMask("types")
 	PlaceFieldMaskOpeningHours         = PlaceFieldMask("regularOpeningHours")
+	PlaceFieldMaskCurrentOpeningHours  = PlaceFieldMask("currentOpeningHours")
+	PlaceFieldMaskSecondaryHours       = PlaceFieldMask("regularSecondaryOpeningHours")
+	PlaceFieldMaskTimezone             = PlaceFieldMask("timeZone")
+	PlaceFieldMaskUtcOffset            = PlaceFieldMask("utcOffsetMinutes")
+	PlaceFieldMaskPrimaryType          = PlaceFieldMask("primaryType")
+	PlaceFieldMaskAddressComponents    = PlaceFieldMask("addressComponents")
 )
 const MaskNextPageToken = "nextPageToken"
+const MaskRoutingSummaries = "routingSummaries"
+
+type TravelMode string
+
+const (
+	TravelModeDrive      = TravelMode("DRIVE")
+	TravelModeBicycle    = TravelMode("BICYCLE")
+	TravelModeWalk       = TravelMode("WALK")
+	TravelModeTwoWheeler = TravelMode("TWO_WHEELER")
+)
+
+type RoutingPreference string
+
+const (
+	RoutingPreferenceTrafficUnaware      = RoutingPreference("TRAFFIC_UNAWARE")
+	RoutingPreferenceTrafficAware        = RoutingPreference("TRAFFIC_AWARE")
+	RoutingPreferenceTrafficAwareOptimal = RoutingPreference("TRAFFIC_AWARE_OPTIMAL")
+)
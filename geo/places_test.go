@@ -2,7 +2,11 @@ package geo
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -71,13 +75,15 @@ func Test_TextSearch_Restriction(t *testing.T) {
 	testGeoClient := GeoClient{testclient}
 	ctx := context.Background()
 	textQuery := "bowling arena"
-	locationRestriction := RectangularRestriction{Rectangle: Rectangle{High: Location{Latitude: 44.711211, Longitude: -63.54319}, Low: Location{Latitude: 44.581167, Longitude: -63.72259}}} // Give Low and High value correctly from viewport object in Geodecode API
-	req := TextSearchRequest{TextQuery: textQuery, LocationRestriction: &locationRestriction, PageSize: 5}
+	viewport := LatLngBounds{NorthEast: LatLng{Lat: 44.711211, Lng: -63.54319}, SouthWest: LatLng{Lat: 44.581167, Lng: -63.72259}} // Halifax viewport from the Geocoding API
+	req := TextSearchRequest{TextQuery: textQuery, PageSize: 5}
 	fieldMask := []PlaceFieldMask{PlaceFieldMaskBusinessStatus, PlaceFieldMaskFormattedAddress, PlaceFieldMaskDispName, PlaceFieldMaskPlaceID, PlaceFieldMaskTypes, PlaceFieldMaskOpeningHours}
 	header := PlacesHeader{FieldMasks: fieldMask, FieldMaskPrefix: true, TokenMask: ""}
-	resp, err := testGeoClient.TextSearch(ctx, &req, &header)
-	assert.Error(t, err) // TODO: Issue giving viewport as locationrestriction. HTTP 400
-	assert.Nil(t, resp)
+	resp, err := testGeoClient.TextSearchInViewport(ctx, viewport, &req, &header)
+	assert.NoError(t, err)
+	for _, place := range resp.Places {
+		assert.True(t, viewport.Rectangle().Contains(place.Location))
+	}
 }
 
 func Test_PlaceDetails(t *testing.T) {
@@ -98,3 +104,43 @@ func Test_PlaceDetails(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 }
+
+// Testing response level masks such as routingSummaries are added without the places prefix
+func Test_PlacesHeader_ResponseMasks(t *testing.T) {
+	header := PlacesHeader{FieldMasks: []PlaceFieldMask{PlaceFieldMaskDispName}, FieldMaskPrefix: true, ResponseMasks: []string{MaskRoutingSummaries}}
+	assert.Equal(t, "places.displayName,routingSummaries", header.Headers()["X-Goog-FieldMask"])
+}
+
+// Testing a search along a route sends the polyline and routing parameters and decodes a routing summary per place
+func Test_TextSearch_AlongRoute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "_p~iF~ps|U", req.SearchAlongRouteParameters.Polyline.EncodedPolyline)
+		assert.Equal(t, RoutingParameters{Origin: &Location{Latitude: 44.6, Longitude: -63.5}, TravelMode: TravelModeDrive}, *req.RoutingParameters)
+		assert.Contains(t, r.Header.Get("X-Goog-FieldMask"), MaskRoutingSummaries)
+		fmt.Fprint(w, `{
+			"places": [{"id": "a"}, {"id": "b"}],
+			"routingSummaries": [{"legs": [{"duration": "60s", "distanceMeters": 500}]}, {"legs": [{"duration": "300s", "distanceMeters": 2500}]}]
+		}`)
+	}))
+	defer srv.Close()
+	c, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	geoClient := GeoClient{c}
+
+	req := TextSearchRequest{
+		TextQuery:                  "coffee",
+		SearchAlongRouteParameters: &SearchAlongRouteParameters{Polyline: Polyline{EncodedPolyline: "_p~iF~ps|U"}},
+		RoutingParameters:          &RoutingParameters{Origin: &Location{Latitude: 44.6, Longitude: -63.5}, TravelMode: TravelModeDrive},
+	}
+	resp, err := geoClient.TextSearch(context.Background(), &req, &PlacesHeader{FieldMaskPrefix: true, ResponseMasks: []string{MaskRoutingSummaries}})
+	assert.NoError(t, err)
+	assert.Len(t, resp.Places, 2)
+	assert.Len(t, resp.RoutingSummaries, 2)
+	assert.Equal(t, "300s", resp.RoutingSummaries[1].Legs[0].Duration)
+
+	req.SearchAlongRouteParameters.Polyline.EncodedPolyline = ""
+	_, err = geoClient.TextSearch(context.Background(), &req, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,286 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Open Location Code (plus code) encoding and decoding, following the reference
+// specification at https://github.com/google/open-location-code/blob/main/Documentation/Specification/specification.md
+
+const (
+	olcAlphabet        = "23456789CFGHJMPQRVWX"
+	olcSeparator       = '+'
+	olcSeparatorPos    = 8
+	olcPadding         = '0'
+	olcEncBase         = 20
+	olcPairCodeLen     = 10
+	olcGridCodeLen     = 5
+	olcGridRows        = 5
+	olcGridCols        = 4
+	olcMaxCodeLen      = olcPairCodeLen + olcGridCodeLen
+	olcMinTrimmableLen = 6
+	// Integer units per degree once every digit of a maximum length code is used
+	olcFinalLatPrecision = 8000 * 3125 // pair precision * gridRows^gridCodeLen
+	olcFinalLngPrecision = 8000 * 1024 // pair precision * gridCols^gridCodeLen
+)
+
+// PlusCodeLength is the default code length. A 10 digit code is a cell about 14x14 meters
+const PlusCodeLength = olcPairCodeLen
+
+// ErrInvalidPlusCode is returned for strings that are not valid plus codes
+var ErrInvalidPlusCode = errors.New("pluscode: invalid code")
+
+// CodeArea is the cell a plus code represents
+type CodeArea struct {
+	LatLo, LngLo, LatHi, LngHi float64
+	Length                     int // Number of digits in the code, excluding padding
+}
+
+// Center returns the middle of the cell
+func (a CodeArea) Center() Location {
+	return Location{
+		Latitude:  math.Min((a.LatLo+a.LatHi)/2, 90),
+		Longitude: math.Min((a.LngLo+a.LngHi)/2, 180),
+	}
+}
+
+// Rectangle returns the bounds of the cell
+func (a CodeArea) Rectangle() Rectangle {
+	return Rectangle{Low: Location{Latitude: a.LatLo, Longitude: a.LngLo}, High: Location{Latitude: a.LatHi, Longitude: a.LngHi}}
+}
+
+// IsValidPlusCode reports whether code is a valid full or short plus code
+func IsValidPlusCode(code string) bool {
+	code = strings.ToUpper(code)
+	sep := strings.IndexByte(code, olcSeparator)
+	if sep < 0 || sep != strings.LastIndexByte(code, olcSeparator) || len(code) == 1 || sep > olcSeparatorPos || sep%2 == 1 {
+		return false
+	}
+	if len(code)-sep-1 == 1 { // a single digit after the separator is never valid
+		return false
+	}
+	if pad := strings.IndexByte(code, olcPadding); pad >= 0 {
+		// Padding is only allowed in full codes, ending at the separator, with nothing after it
+		if sep < olcSeparatorPos || pad == 0 || pad%2 == 1 || len(code) > sep+1 {
+			return false
+		}
+		if strings.Trim(code[pad:sep], string(olcPadding)) != "" {
+			return false
+		}
+	}
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if c != olcSeparator && c != olcPadding && strings.IndexByte(olcAlphabet, c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsShortPlusCode reports whether code is a valid short code, one with leading digits removed
+// that needs a reference location to recover. Eg: CWC8+R9
+func IsShortPlusCode(code string) bool {
+	return IsValidPlusCode(code) && strings.IndexByte(code, olcSeparator) < olcSeparatorPos
+}
+
+// IsFullPlusCode reports whether code is a valid full code that can be decoded without a reference location
+func IsFullPlusCode(code string) bool {
+	if !IsValidPlusCode(code) || IsShortPlusCode(code) {
+		return false
+	}
+	code = strings.ToUpper(code)
+	// The first latitude digit can't exceed 180 degrees and the first longitude digit 360
+	if strings.IndexByte(olcAlphabet, code[0])*olcEncBase >= 180 {
+		return false
+	}
+	if len(code) > 1 && strings.IndexByte(olcAlphabet, code[1])*olcEncBase >= 360 {
+		return false
+	}
+	return true
+}
+
+// EncodePlusCode returns the plus code of the given length for a location. Lengths below 10 must be even;
+// odd lengths are rounded up. Latitude is clipped to [-90, 90] and longitude normalised.
+func EncodePlusCode(lat, lng float64, length int) (string, error) {
+	if length < 2 {
+		return "", fmt.Errorf("pluscode: invalid code length %d", length)
+	}
+	if length < olcPairCodeLen && length%2 == 1 {
+		length++
+	}
+	length = min(length, olcMaxCodeLen)
+	latVal := int64(math.Round(lat*olcFinalLatPrecision)) + 90*olcFinalLatPrecision
+	latVal = max(0, min(latVal, 180*olcFinalLatPrecision-1)) // the north pole belongs to the cell below it
+	lngVal := int64(math.Round(lng*olcFinalLngPrecision)) + 180*olcFinalLngPrecision
+	lngVal %= 360 * olcFinalLngPrecision
+	if lngVal < 0 {
+		lngVal += 360 * olcFinalLngPrecision
+	}
+
+	// Grid digits are produced least significant first
+	grid := make([]byte, olcGridCodeLen)
+	for i := olcGridCodeLen - 1; i >= 0; i-- {
+		grid[i] = olcAlphabet[(latVal%olcGridRows)*olcGridCols+lngVal%olcGridCols]
+		latVal /= olcGridRows
+		lngVal /= olcGridCols
+	}
+	pairs := make([]byte, olcPairCodeLen)
+	for i := olcPairCodeLen/2 - 1; i >= 0; i-- {
+		pairs[i*2] = olcAlphabet[latVal%olcEncBase]
+		pairs[i*2+1] = olcAlphabet[lngVal%olcEncBase]
+		latVal /= olcEncBase
+		lngVal /= olcEncBase
+	}
+	digits := append(pairs, grid...)[:length]
+	if length < olcSeparatorPos {
+		return string(digits) + strings.Repeat(string(olcPadding), olcSeparatorPos-length) + string(olcSeparator), nil
+	}
+	return string(digits[:olcSeparatorPos]) + string(olcSeparator) + string(digits[olcSeparatorPos:]), nil
+}
+
+// DecodePlusCode returns the cell a full plus code represents
+func DecodePlusCode(code string) (CodeArea, error) {
+	if !IsFullPlusCode(code) {
+		return CodeArea{}, ErrInvalidPlusCode
+	}
+	digits := strings.NewReplacer(string(olcSeparator), "", string(olcPadding), "").Replace(strings.ToUpper(code))
+	digits = digits[:min(len(digits), olcMaxCodeLen)]
+
+	// Work in integer units of the final precision to avoid floating point drift.
+	// Sizes start at 400 degrees so the first pair of digits is 20 degrees each
+	var lat, lng int64
+	latSize, lngSize := int64(olcEncBase*olcEncBase*olcFinalLatPrecision), int64(olcEncBase*olcEncBase*olcFinalLngPrecision)
+	for i := 0; i < len(digits) && i < olcPairCodeLen; i += 2 {
+		latSize /= olcEncBase
+		lngSize /= olcEncBase
+		lat += int64(strings.IndexByte(olcAlphabet, digits[i])) * latSize
+		if i+1 < len(digits) {
+			lng += int64(strings.IndexByte(olcAlphabet, digits[i+1])) * lngSize
+		}
+	}
+	for i := olcPairCodeLen; i < len(digits); i++ {
+		latSize /= olcGridRows
+		lngSize /= olcGridCols
+		d := int64(strings.IndexByte(olcAlphabet, digits[i]))
+		lat += d / olcGridCols * latSize
+		lng += d % olcGridCols * lngSize
+	}
+	return CodeArea{
+		LatLo:  float64(lat)/olcFinalLatPrecision - 90,
+		LngLo:  float64(lng)/olcFinalLngPrecision - 180,
+		LatHi:  float64(lat+latSize)/olcFinalLatPrecision - 90,
+		LngHi:  float64(lng+lngSize)/olcFinalLngPrecision - 180,
+		Length: len(digits),
+	}, nil
+}
+
+// ShortenPlusCode removes as many leading digits from a full code as the reference location allows.
+// The result can be recovered with RecoverNearestPlusCode from any location near the reference.
+func ShortenPlusCode(code string, lat, lng float64) (string, error) {
+	if !IsFullPlusCode(code) {
+		return "", ErrInvalidPlusCode
+	}
+	if strings.IndexByte(code, olcPadding) >= 0 {
+		return "", errors.New("pluscode: cannot shorten padded codes")
+	}
+	code = strings.ToUpper(code)
+	area, err := DecodePlusCode(code)
+	if err != nil {
+		return "", err
+	}
+	if area.Length < olcMinTrimmableLen {
+		return "", fmt.Errorf("pluscode: code length must be at least %d to shorten", olcMinTrimmableLen)
+	}
+	center := area.Center()
+	lat = math.Max(-90, math.Min(lat, 90))
+	dLng := math.Abs(center.Longitude - lng)
+	dLng = math.Min(dLng, 360-dLng)
+	distance := math.Max(math.Abs(center.Latitude-lat), dLng)
+	// Only remove digits when the reference is well within the cell they describe. 0.3 instead of 0.5 leaves a safety margin
+	for i := 4; i >= 1; i-- {
+		if distance < pairResolution(i*2)*0.3 {
+			return code[i*2:], nil
+		}
+	}
+	return code, nil
+}
+
+// RecoverNearestPlusCode returns the full code closest to the reference location that matches a short code.
+// Full codes are returned unchanged.
+func RecoverNearestPlusCode(code string, lat, lng float64) (string, error) {
+	if IsFullPlusCode(code) {
+		return strings.ToUpper(code), nil
+	}
+	if !IsShortPlusCode(code) {
+		return "", ErrInvalidPlusCode
+	}
+	code = strings.ToUpper(code)
+	lat = math.Max(-90, math.Min(lat, 90))
+	padLen := olcSeparatorPos - strings.IndexByte(code, olcSeparator)
+	resolution := pairResolution(padLen)
+	// Take the missing digits from the reference location, then move a cell if that lands too far away
+	ref, err := EncodePlusCode(lat, lng, olcPairCodeLen)
+	if err != nil {
+		return "", err
+	}
+	area, err := DecodePlusCode(ref[:padLen] + code)
+	if err != nil {
+		return "", err
+	}
+	center := area.Center()
+	if lat+resolution/2 < center.Latitude && center.Latitude-resolution >= -90 {
+		center.Latitude -= resolution
+	} else if lat-resolution/2 > center.Latitude && center.Latitude+resolution <= 90 {
+		center.Latitude += resolution
+	}
+	if lng+resolution/2 < center.Longitude {
+		center.Longitude -= resolution
+	} else if lng-resolution/2 > center.Longitude {
+		center.Longitude += resolution
+	}
+	return EncodePlusCode(center.Latitude, center.Longitude, area.Length)
+}
+
+// Size in degrees of the cell described by the first digits of a code. Eg: 2 digits is 20 degrees
+func pairResolution(digits int) float64 {
+	return math.Pow(olcEncBase, float64(2-digits/2))
+}
+
+// GeocodePlusCode resolves a full plus code locally into a geocoding result without calling the
+// Geocoding API. The result's location is the center of the code's cell and its bounds the cell.
+func GeocodePlusCode(code string) (GeocodingResponse, error) {
+	area, err := DecodePlusCode(code)
+	if err != nil {
+		return GeocodingResponse{}, err
+	}
+	bounds := LatLngBounds{
+		SouthWest: LatLng{Lat: area.LatLo, Lng: area.LngLo},
+		NorthEast: LatLng{Lat: area.LatHi, Lng: area.LngHi},
+	}
+	result := GeocodingResult{
+		FormattedAddress: strings.ToUpper(code),
+		Geometry: AddressGeometry{
+			Location:     area.Center().LatLng(),
+			LocationType: string(GeocodeAccuracyGC),
+			Bounds:       bounds,
+			Viewport:     bounds,
+		},
+		Types:    []string{"plus_code"},
+		PlusCode: PlusCode{GlobalCode: strings.ToUpper(code)},
+	}
+	return GeocodingResponse{Results: []GeocodingResult{result}}, nil
+}
+
+// WithPlusCode returns p with its plus code computed from its location when Google did not supply one
+func WithPlusCode(p Place) Place {
+	if (p.PlusCode != nil && p.PlusCode.GlobalCode != "") || (p.Location == Location{}) {
+		return p
+	}
+	if code, err := EncodePlusCode(p.Location.Latitude, p.Location.Longitude, PlusCodeLength); err == nil {
+		p.PlusCode = &PlacePlusCode{GlobalCode: code}
+	}
+	return p
+}
@@ -0,0 +1,190 @@
+package geo
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing encoding against the reference implementation's test data
+func Test_EncodePlusCode(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		length   int
+		code     string
+	}{
+		{20.375, 2.775, 6, "7FG49Q00+"},
+		{20.3700625, 2.7821875, 10, "7FG49QCJ+2V"},
+		{20.3701125, 2.782234375, 11, "7FG49QCJ+2VX"},
+		{20.3701135, 2.78223535156, 13, "7FG49QCJ+2VXGJ"},
+		{47.0000625, 8.0000625, 10, "8FVC2222+22"},
+		{-41.2730625, 174.7859375, 10, "4VCPPQGP+Q9"},
+		{0.5, -179.5, 4, "62G20000+"},
+		{-89.5, -179.5, 4, "22220000+"},
+		{20.5, 2.5, 4, "7FG40000+"},
+		{-89.9999375, -179.9999375, 10, "22222222+22"},
+		{0.5, 179.5, 4, "6VGX0000+"},
+		{1, 1, 11, "6FH32222+222"},
+		{90, 1, 4, "CFX30000+"},
+		{92, 1, 4, "CFX30000+"},
+		{1, 180, 4, "62H20000+"},
+		{1, 181, 4, "62H30000+"},
+	}
+	for _, tt := range tests {
+		code, err := EncodePlusCode(tt.lat, tt.lng, tt.length)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.code, code)
+	}
+	_, err := EncodePlusCode(1, 1, 1)
+	assert.Error(t, err)
+}
+
+// Testing decoding returns the expected cell
+func Test_DecodePlusCode(t *testing.T) {
+	area, err := DecodePlusCode("7fg49qcj+2v")
+	assert.NoError(t, err)
+	assert.InDelta(t, 20.37, area.LatLo, 1e-9)
+	assert.InDelta(t, 2.782125, area.LngLo, 1e-9)
+	assert.InDelta(t, 20.370125, area.LatHi, 1e-9)
+	assert.InDelta(t, 2.78225, area.LngHi, 1e-9)
+	assert.Equal(t, 10, area.Length)
+
+	area, err = DecodePlusCode("7FG40000+")
+	assert.NoError(t, err)
+	assert.Equal(t, CodeArea{LatLo: 20, LngLo: 2, LatHi: 21, LngHi: 3, Length: 4}, area)
+
+	_, err = DecodePlusCode("9QCJ+2VX")
+	assert.ErrorIs(t, err, ErrInvalidPlusCode)
+}
+
+// Testing decoding the code for a location gives a cell around that location. Encoding rounds to the
+// finest precision (about 4e-8 degrees) so locations may sit that far outside the cell
+func Test_PlusCode_RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		lat, lng := r.Float64()*179.9-89.95, r.Float64()*360-180
+		for _, length := range []int{4, 8, 10, 11, 15} {
+			code, err := EncodePlusCode(lat, lng, length)
+			assert.NoError(t, err)
+			area, err := DecodePlusCode(code)
+			assert.NoError(t, err)
+			assert.True(t, area.LatLo <= lat+1e-7 && lat < area.LatHi+1e-7, code)
+			assert.True(t, area.LngLo <= lng+1e-7 && lng < area.LngHi+1e-7, code)
+		}
+	}
+}
+
+// Testing code validation
+func Test_PlusCode_Validity(t *testing.T) {
+	tests := []struct {
+		code               string
+		valid, short, full bool
+	}{
+		{"8FWC2345+G6", true, false, true},
+		{"8FWC2345+G6G", true, false, true},
+		{"8fwc2345+", true, false, true},
+		{"8FWCX400+", true, false, true},
+		{"WC2345+G6g", true, true, false},
+		{"2345+G6", true, true, false},
+		{"45+G6", true, true, false},
+		{"+G6", true, true, false},
+		{"G+", false, false, false},
+		{"+", false, false, false},
+		{"8FWC2345+G", false, false, false},
+		{"8FWC2_45+G6", false, false, false},
+		{"8FWC2η45+G6", false, false, false},
+		{"8FWC2345+G6+", false, false, false},
+		{"8FWC2345G6+", false, false, false},
+		{"8FWC2300+G6", false, false, false},
+		{"WC2300+G6g", false, false, false},
+		{"WC2345+G", false, false, false},
+		{"WC2300+", false, false, false},
+		{"C2XXXXXX+", true, false, true},
+		{"F2222222+", true, false, false},
+		{"2X222222+", true, false, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.valid, IsValidPlusCode(tt.code), tt.code)
+		assert.Equal(t, tt.short, IsShortPlusCode(tt.code), tt.code)
+		assert.Equal(t, tt.full, IsFullPlusCode(tt.code), tt.code)
+	}
+}
+
+// Testing shortening against a reference location and recovering the nearest full code
+func Test_ShortenPlusCode(t *testing.T) {
+	tests := []struct {
+		code     string
+		lat, lng float64
+		short    string
+	}{
+		{"9C3W9QCJ+2VX", 51.3701125, -1.217765625, "+2VX"},
+		{"9C3W9QCJ+2VX", 51.3708675, -1.217765625, "CJ+2VX"},
+		{"9C3W9QCJ+2VX", 51.3693575, -1.217765625, "CJ+2VX"},
+		{"9C3W9QCJ+2VX", 51.3701125, -1.2188, "CJ+2VX"},
+		{"9C3W9QCJ+2VX", 51.3852125, -1.217765625, "9QCJ+2VX"},
+		{"9C3W9QCJ+2VX", 51.5, -1.2, "9QCJ+2VX"},
+		{"9C3W9QCJ+2VX", 48.8, 2.3, "3W9QCJ+2VX"},
+		{"9C3W9QCJ+2VX", -33.9, 151.2, "9C3W9QCJ+2VX"},
+	}
+	for _, tt := range tests {
+		short, err := ShortenPlusCode(tt.code, tt.lat, tt.lng)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.short, short, tt.code)
+		full, err := RecoverNearestPlusCode(short, tt.lat, tt.lng)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.code, full)
+	}
+	_, err := ShortenPlusCode("9C3W0000+", 51.3, -1.2)
+	assert.Error(t, err)
+	_, err = RecoverNearestPlusCode("not a code", 51.3, -1.2)
+	assert.ErrorIs(t, err, ErrInvalidPlusCode)
+}
+
+// Testing recovery picks the neighbouring cell when the reference is near a cell edge
+func Test_RecoverNearestPlusCode(t *testing.T) {
+	tests := []struct {
+		short    string
+		lat, lng float64
+		full     string
+	}{
+		{"9QCJ+2VX", 51.3701125, -1.217765625, "9C3W9QCJ+2VX"},
+		{"2222+22", 89.6, 0.0, "CFX22222+22"},
+		{"XXXX+XX", 89.6, 0.0, ""}, // latitude can't move past the pole
+		{"X2X2+X2", 0.0, 179.999, ""},
+		{"2X2X+2X", 0.0, -179.999, ""},
+	}
+	for _, tt := range tests[:2] {
+		full, err := RecoverNearestPlusCode(tt.short, tt.lat, tt.lng)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.full, full, tt.short)
+	}
+	// Properties of the rest: the recovered code matches the short code and is within a cell (1 degree) of the reference
+	for _, tt := range tests[2:] {
+		full, err := RecoverNearestPlusCode(tt.short, tt.lat, tt.lng)
+		assert.NoError(t, err)
+		assert.True(t, IsFullPlusCode(full))
+		assert.Equal(t, tt.short, full[len(full)-len(tt.short):])
+		area, err := DecodePlusCode(full)
+		assert.NoError(t, err)
+		assert.Less(t, distanceMeters(area.Center(), Location{Latitude: tt.lat, Longitude: tt.lng}), 111200.0, tt.short)
+	}
+}
+
+// Testing a plus code geocodes to its cell and places without one get it computed
+func Test_GeocodePlusCode(t *testing.T) {
+	resp, err := GeocodePlusCode("87c4vxgq+68")
+	assert.NoError(t, err)
+	assert.Len(t, resp.Results, 1)
+	result := resp.Results[0]
+	assert.Equal(t, "87C4VXGQ+68", result.PlusCode.GlobalCode)
+	assert.True(t, result.Geometry.Bounds.Rectangle().Contains(result.Geometry.Location.Location()))
+	_, err = GeocodePlusCode("VXGQ+68")
+	assert.ErrorIs(t, err, ErrInvalidPlusCode)
+
+	place := WithPlusCode(Place{Location: result.Geometry.Location.Location()})
+	assert.Equal(t, "87C4VXGQ+68", place.PlusCode.GlobalCode)
+	supplied := &PlacePlusCode{GlobalCode: "87C4VXGQ+69", CompoundCode: "VXGQ+69 Boston"}
+	assert.Same(t, supplied, WithPlusCode(Place{Location: result.Geometry.Location.Location(), PlusCode: supplied}).PlusCode)
+	assert.Nil(t, WithPlusCode(Place{}).PlusCode)
+}
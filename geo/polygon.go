@@ -0,0 +1,261 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/geolocate/geo/geometry"
+)
+
+// Polygon is an area enclosed by its first ring. Any further rings are holes cut out of it.
+// Rings are closed: the last point joins back to the first.
+type Polygon struct {
+	Rings [][]Location
+}
+
+// MultiPolygon is a set of polygons treated as one area. Eg: delivery zones
+type MultiPolygon []Polygon
+
+// GeoJSON object as sent by clients. Only Polygon and MultiPolygon geometries,
+// optionally wrapped in a Feature, are supported
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+}
+
+// ParseGeoJSON reads a GeoJSON Polygon, MultiPolygon or a Feature holding either into a MultiPolygon.
+// GeoJSON positions are [longitude, latitude].
+func ParseGeoJSON(data []byte) (MultiPolygon, error) {
+	var g geoJSON
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	if g.Type == "Feature" {
+		if g.Geometry == nil {
+			return nil, errors.New("geojson: Feature has no geometry")
+		}
+		g = *g.Geometry
+	}
+	var area MultiPolygon
+	switch g.Type {
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		polygon, err := polygonFromCoordinates(coords)
+		if err != nil {
+			return nil, err
+		}
+		area = MultiPolygon{polygon}
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		for _, c := range coords {
+			polygon, err := polygonFromCoordinates(c)
+			if err != nil {
+				return nil, err
+			}
+			area = append(area, polygon)
+		}
+	default:
+		return nil, fmt.Errorf("geojson: unsupported type %q, expected Polygon or MultiPolygon", g.Type)
+	}
+	if len(area) == 0 {
+		return nil, errors.New("geojson: no polygons found")
+	}
+	return area, nil
+}
+
+func polygonFromCoordinates(coords [][][]float64) (Polygon, error) {
+	if len(coords) == 0 {
+		return Polygon{}, errors.New("geojson: polygon has no rings")
+	}
+	var polygon Polygon
+	for _, ring := range coords {
+		if len(ring) < 3 {
+			return Polygon{}, errors.New("geojson: polygon ring needs at least 3 positions")
+		}
+		var locations []Location
+		for _, position := range ring {
+			if len(position) < 2 {
+				return Polygon{}, errors.New("geojson: position needs longitude and latitude")
+			}
+			locations = append(locations, Location{Latitude: position[1], Longitude: position[0]})
+		}
+		polygon.Rings = append(polygon.Rings, locations)
+	}
+	return polygon, nil
+}
+
+// Contains reports whether l lies inside the outer ring and outside every hole
+func (p Polygon) Contains(l Location) bool {
+	if len(p.Rings) == 0 || !ringContains(p.Rings[0], l) {
+		return false
+	}
+	for _, hole := range p.Rings[1:] {
+		if ringContains(hole, l) {
+			return false
+		}
+	}
+	return true
+}
+
+// Bounds returns the rectangle enclosing the outer ring
+func (p Polygon) Bounds() Rectangle {
+	if len(p.Rings) == 0 || len(p.Rings[0]) == 0 {
+		return Rectangle{}
+	}
+	bounds := Rectangle{Low: p.Rings[0][0], High: p.Rings[0][0]}
+	for _, l := range p.Rings[0] {
+		bounds = bounds.extend(l)
+	}
+	return bounds
+}
+
+// IntersectsRectangle reports whether any part of the polygon overlaps r
+func (p Polygon) IntersectsRectangle(r Rectangle) bool {
+	if len(p.Rings) == 0 || !p.Bounds().Intersects(r) {
+		return false
+	}
+	outer := p.Rings[0]
+	for _, l := range outer {
+		if r.Contains(l) {
+			return true
+		}
+	}
+	corners := r.corners()
+	for _, corner := range corners {
+		if ringContains(outer, corner) {
+			return true
+		}
+	}
+	for i := range outer {
+		a, b := outer[i], outer[(i+1)%len(outer)]
+		for j := range corners {
+			if segmentsIntersect(a, b, corners[j], corners[(j+1)%len(corners)]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Contains reports whether l lies inside any of the polygons
+func (m MultiPolygon) Contains(l Location) bool {
+	for _, p := range m {
+		if p.Contains(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the rectangle enclosing all the polygons
+func (m MultiPolygon) Bounds() Rectangle {
+	if len(m) == 0 {
+		return Rectangle{}
+	}
+	bounds := m[0].Bounds()
+	for _, p := range m[1:] {
+		if len(p.Rings) == 0 {
+			continue
+		}
+		for _, l := range p.Rings[0] {
+			bounds = bounds.extend(l)
+		}
+	}
+	return bounds
+}
+
+// IntersectsRectangle reports whether any of the polygons overlaps r
+func (m MultiPolygon) IntersectsRectangle(r Rectangle) bool {
+	for _, p := range m {
+		if p.IntersectsRectangle(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterPlaces returns the places whose location lies inside area
+func FilterPlaces(places []Place, area SearchArea) []Place {
+	var inside []Place
+	for _, place := range places {
+		if area.Contains(place.Location) {
+			inside = append(inside, place)
+		}
+	}
+	return inside
+}
+
+// TextSearchInArea runs a text search restricted to the rectangle enclosing area and drops
+// places that fall outside area itself. Use it for polygon shaped zones that the Places API
+// cannot take as a restriction. Areas crossing the antimeridian or larger than one restriction
+// are searched as in TextSearchInViewport, without paging.
+func (c *GeoClient) TextSearchInArea(ctx context.Context, area SearchArea, r *TextSearchRequest, h *PlacesHeader) (PlacesSearchResponse, error) {
+	if area == nil {
+		return PlacesSearchResponse{}, errors.New("maps: Required field area missing")
+	}
+	return c.textSearchInBounds(ctx, LatLngBoundsFromGeometry(area.Bounds().Geometry()), area, r, h)
+}
+
+// Ray casting test. Count edges crossed by a ray travelling east from l. Each edge takes the short
+// way round so rings crossing the antimeridian stay in one piece, and l is moved next to the ring
+func ringContains(ring []Location, l Location) bool {
+	if len(ring) == 0 {
+		return false
+	}
+	unwrapped := make([]Location, len(ring))
+	prev := ring[0].Longitude
+	for i, p := range ring {
+		p.Longitude = prev + geometry.NormalizeLng(p.Longitude-prev)
+		unwrapped[i], prev = p, p.Longitude
+	}
+	l.Longitude = ring[0].Longitude + geometry.NormalizeLng(l.Longitude-ring[0].Longitude)
+	inside := false
+	for i, j := 0, len(unwrapped)-1; i < len(unwrapped); j, i = i, i+1 {
+		a, b := unwrapped[i], unwrapped[j]
+		if (a.Latitude > l.Latitude) != (b.Latitude > l.Latitude) {
+			crossing := (b.Longitude-a.Longitude)*(l.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if l.Longitude < crossing {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Reports whether segment p1-p2 crosses segment q1-q2
+func segmentsIntersect(p1, p2, q1, q2 Location) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	return ((d1 > 0) != (d2 > 0)) && ((d3 > 0) != (d4 > 0))
+}
+
+// Sign of the cross product (b-a)x(c-a). Positive when a,b,c turn counter clockwise
+func orientation(a, b, c Location) float64 {
+	return (b.Longitude-a.Longitude)*(c.Latitude-a.Latitude) - (b.Latitude-a.Latitude)*(c.Longitude-a.Longitude)
+}
+
+// Grow the rectangle to include l, wrapping past the antimeridian when that is narrower
+func (r Rectangle) extend(l Location) Rectangle {
+	return RectangleFromGeometry(r.Geometry().Extend(l.Point()))
+}
+
+// Corners of the rectangle in ring order
+func (r Rectangle) corners() []Location {
+	return []Location{
+		r.Low,
+		{Latitude: r.Low.Latitude, Longitude: r.High.Longitude},
+		r.High,
+		{Latitude: r.High.Latitude, Longitude: r.Low.Longitude},
+	}
+}
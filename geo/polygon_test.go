@@ -0,0 +1,155 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geolocate/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// An L shaped delivery zone with a hole, followed by a separate square zone
+const deliveryZones = `{
+	"type": "Feature",
+	"geometry": {
+		"type": "MultiPolygon",
+		"coordinates": [
+			[
+				[[-63.60, 44.60], [-63.50, 44.60], [-63.50, 44.65], [-63.55, 44.65], [-63.55, 44.70], [-63.60, 44.70], [-63.60, 44.60]],
+				[[-63.59, 44.61], [-63.58, 44.61], [-63.58, 44.62], [-63.59, 44.62], [-63.59, 44.61]]
+			],
+			[
+				[[-63.40, 44.60], [-63.38, 44.60], [-63.38, 44.62], [-63.40, 44.62], [-63.40, 44.60]]
+			]
+		]
+	}
+}`
+
+// Testing GeoJSON parsing and point in polygon checks
+func Test_ParseGeoJSON(t *testing.T) {
+	zones, err := ParseGeoJSON([]byte(deliveryZones))
+	assert.NoError(t, err)
+	assert.Len(t, zones, 2)
+	tests := []struct {
+		name   string
+		l      Location
+		inside bool
+	}{
+		{"lower arm", Location{Latitude: 44.63, Longitude: -63.52}, true},
+		{"upper arm", Location{Latitude: 44.68, Longitude: -63.58}, true},
+		{"notch of the L", Location{Latitude: 44.68, Longitude: -63.52}, false},
+		{"hole", Location{Latitude: 44.615, Longitude: -63.585}, false},
+		{"second zone", Location{Latitude: 44.61, Longitude: -63.39}, true},
+		{"between zones", Location{Latitude: 44.61, Longitude: -63.45}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.inside, zones.Contains(tt.l), tt.name)
+	}
+	assert.Equal(t, Rectangle{Low: Location{Latitude: 44.60, Longitude: -63.60}, High: Location{Latitude: 44.70, Longitude: -63.38}}, zones.Bounds())
+	assert.False(t, zones.IntersectsRectangle(Rectangle{Low: Location{Latitude: 44.67, Longitude: -63.54}, High: Location{Latitude: 44.69, Longitude: -63.51}}))
+	assert.True(t, zones.IntersectsRectangle(Rectangle{Low: Location{Latitude: 44.64, Longitude: -63.54}, High: Location{Latitude: 44.69, Longitude: -63.51}}))
+}
+
+// Testing unsupported or malformed GeoJSON is rejected
+func Test_ParseGeoJSON_Invalid(t *testing.T) {
+	_, err := ParseGeoJSON([]byte(`{"type": "Point", "coordinates": [-63.5, 44.6]}`))
+	assert.Error(t, err)
+	_, err = ParseGeoJSON([]byte(`{"type": "Polygon", "coordinates": [[[-63.5, 44.6], [-63.4, 44.6]]]}`))
+	assert.Error(t, err)
+	_, err = ParseGeoJSON([]byte(`{"type": "Feature"}`))
+	assert.Error(t, err)
+}
+
+// Testing CoverageSearch over a polygon only returns places inside the zones
+func Test_CoverageSearch_Polygon(t *testing.T) {
+	zones, err := ParseGeoJSON([]byte(deliveryZones))
+	assert.NoError(t, err)
+	var places []Place
+	for i := 0; i < 25; i++ {
+		for j := 0; j < 25; j++ {
+			places = append(places, Place{Id: string(rune('a'+i)) + string(rune('a'+j)), Location: Location{Latitude: 44.60 + float64(i)*0.004, Longitude: -63.60 + float64(j)*0.009}})
+		}
+	}
+	srv := newNearbySearchServer(t, places)
+	defer srv.Close()
+	testclient, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	testGeoClient := GeoClient{Client: testclient}
+	resp, err := testGeoClient.CoverageSearch(context.Background(), zones, &NearbySearchRequest{}, nil, CoverageOptions{MaxDepth: 8})
+	assert.NoError(t, err)
+	assert.Equal(t, len(FilterPlaces(places, zones)), len(resp.Places))
+	assert.Equal(t, 0, resp.Stats.Truncated)
+}
+
+func placeIDs(places []Place) []string {
+	var ids []string
+	for _, p := range places {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+// Testing TextSearchInArea restricts the search to the zones' bounding box and drops places outside the polygons
+func Test_TextSearchInArea(t *testing.T) {
+	var restrictions []Rectangle
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "pizza", req.TextQuery)
+		assert.Nil(t, req.LocationBias)
+		assert.Contains(t, r.Header.Get("X-Goog-FieldMask"), "places.location")
+		restrictions = append(restrictions, req.LocationRestriction.Rectangle)
+		json.NewEncoder(w).Encode(PlacesSearchResponse{Places: []Place{
+			{Id: "zone", Location: Location{Latitude: 44.63, Longitude: -63.52}},
+			{Id: "hole", Location: Location{Latitude: 44.615, Longitude: -63.585}},
+			{Id: "notch", Location: Location{Latitude: 44.68, Longitude: -63.52}}, // inside the box, outside the L
+			{Id: "square", Location: Location{Latitude: 44.61, Longitude: -63.39}},
+		}, NextPageToken: "token"})
+	}))
+	defer srv.Close()
+	c, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	geoClient := GeoClient{c}
+
+	zones, err := ParseGeoJSON([]byte(deliveryZones))
+	assert.NoError(t, err)
+	resp, err := geoClient.TextSearchInArea(context.Background(), zones, &TextSearchRequest{TextQuery: "pizza", LocationBias: &LocationRestriction{}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Rectangle{{Low: Location{Latitude: 44.60, Longitude: -63.60}, High: Location{Latitude: 44.70, Longitude: -63.38}}}, restrictions)
+	assert.Equal(t, []string{"zone", "square"}, placeIDs(resp.Places))
+	assert.Equal(t, "token", resp.NextPageToken)
+}
+
+// Testing a zone crossing the antimeridian is searched either side of it rather than around the globe
+func Test_TextSearchInArea_Antimeridian(t *testing.T) {
+	var restrictions []Rectangle
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		restrictions = append(restrictions, req.LocationRestriction.Rectangle)
+		json.NewEncoder(w).Encode(PlacesSearchResponse{Places: []Place{
+			{Id: "east", Location: Location{Latitude: -17.5, Longitude: 179.8}},
+			{Id: "west", Location: Location{Latitude: -17.5, Longitude: -179.8}},
+			{Id: "far", Location: Location{Latitude: -17.5, Longitude: 0}},
+		}})
+	}))
+	defer srv.Close()
+	c, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	geoClient := GeoClient{c}
+
+	zone, err := ParseGeoJSON([]byte(`{"type": "Polygon", "coordinates": [[[179.5, -18], [-179.5, -18], [-179.5, -17], [179.5, -17], [179.5, -18]]]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, Rectangle{Low: Location{Latitude: -18, Longitude: 179.5}, High: Location{Latitude: -17, Longitude: -179.5}}, zone.Bounds())
+	resp, err := geoClient.TextSearchInArea(context.Background(), zone, &TextSearchRequest{TextQuery: "resort"}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, restrictions, 2)
+	for _, r := range restrictions {
+		assert.LessOrEqual(t, r.Low.Longitude, r.High.Longitude)
+		assert.LessOrEqual(t, r.High.Longitude-r.Low.Longitude, 0.5)
+	}
+	assert.Equal(t, []string{"east", "west"}, placeIDs(resp.Places))
+}
@@ -0,0 +1,178 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/geolocate/geo/geometry"
+)
+
+// Encoded polyline algorithm, as used by the Directions, Routes and Places APIs.
+// See https://developers.google.com/maps/documentation/utilities/polylinealgorithm
+
+// PolylinePrecision is the number of decimal places Google encodes coordinates with. Some
+// services, such as Roads and OSRM, use 6
+const PolylinePrecision = 5
+
+// ErrInvalidPolyline is returned when an encoded polyline is truncated or holds characters outside the encoding
+var ErrInvalidPolyline = errors.New("polyline: invalid encoding")
+
+// EncodePolyline encodes points with the given number of decimal places
+func EncodePolyline(points []Location, precision int) string {
+	factor := math.Pow10(precision)
+	var sb strings.Builder
+	var prevLat, prevLng int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Latitude * factor))
+		lng := int64(math.Round(p.Longitude * factor))
+		encodePolylineValue(&sb, lat-prevLat)
+		encodePolylineValue(&sb, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return sb.String()
+}
+
+// Each value is zig-zag encoded so the sign is the lowest bit, then written 5 bits at a time,
+// least significant first, with 0x20 marking that more chunks follow
+func encodePolylineValue(sb *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		sb.WriteByte(byte(0x20|u&0x1f) + 63)
+		u >>= 5
+	}
+	sb.WriteByte(byte(u) + 63)
+}
+
+// DecodePolyline decodes a polyline encoded with the given number of decimal places
+func DecodePolyline(encoded string, precision int) ([]Location, error) {
+	factor := math.Pow10(precision)
+	var points []Location
+	var lat, lng int64
+	for i := 0; i < len(encoded); {
+		dLat, n, err := decodePolylineValue(encoded[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		dLng, n, err := decodePolylineValue(encoded[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		lat, lng = lat+dLat, lng+dLng
+		points = append(points, Location{Latitude: float64(lat) / factor, Longitude: float64(lng) / factor})
+	}
+	return points, nil
+}
+
+// Read one value, returning it and the number of bytes consumed
+func decodePolylineValue(s string) (int64, int, error) {
+	var u uint64
+	for i := 0; i < len(s) && i < 13; i++ {
+		c := int(s[i]) - 63
+		if c < 0 || c > 0x3f {
+			return 0, 0, ErrInvalidPolyline
+		}
+		u |= uint64(c&0x1f) << (5 * i)
+		if c < 0x20 {
+			v := int64(u >> 1)
+			if u&1 == 1 {
+				v = ^v
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, ErrInvalidPolyline
+}
+
+// NewPolyline encodes points into a Polyline that can be sent to the Places API
+func NewPolyline(points []Location) Polyline {
+	return Polyline{EncodedPolyline: EncodePolyline(points, PolylinePrecision)}
+}
+
+// Locations decodes the points of the polyline
+func (p Polyline) Locations() ([]Location, error) {
+	return DecodePolyline(p.EncodedPolyline, PolylinePrecision)
+}
+
+// PolylineLength returns the length in meters of the path through points
+func PolylineLength(points []Location) float64 {
+	var length float64
+	for i := 1; i < len(points); i++ {
+		length += distanceMeters(points[i-1], points[i])
+	}
+	return length
+}
+
+// SimplifyPolyline drops points with the Douglas-Peucker algorithm so that no removed point lies
+// further than tolerance meters from the simplified path. The first and last points are always kept.
+func SimplifyPolyline(points []Location, tolerance float64) []Location {
+	if len(points) < 3 {
+		return append([]Location{}, points...)
+	}
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	// Ranges still to check, as index pairs of kept points. A stack avoids deep recursion on long routes
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		furthest, maxDistance := -1, tolerance
+		for i := r[0] + 1; i < r[1]; i++ {
+			if d := geometry.DistanceToSegment(points[i].Point(), points[r[0]].Point(), points[r[1]].Point()); d > maxDistance {
+				furthest, maxDistance = i, d
+			}
+		}
+		if furthest < 0 {
+			continue
+		}
+		keep[furthest] = true
+		stack = append(stack, [2]int{r[0], furthest}, [2]int{furthest, r[1]})
+	}
+	var simplified []Location
+	for i, p := range points {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+// SamplePolyline returns points spaced every interval meters along the path, starting with its
+// first point. The last point is always included so the whole path is covered. Eg: search centers along a route
+func SamplePolyline(points []Location, interval float64) []Location {
+	if len(points) == 0 || interval <= 0 {
+		return append([]Location{}, points...)
+	}
+	samples := []Location{points[0]}
+	next := interval // distance from the start of the current segment to the next sample
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		segment := distanceMeters(a, b)
+		for ; next < segment; next += interval {
+			samples = append(samples, LocationFromPoint(geometry.Interpolate(a.Point(), b.Point(), next/segment)))
+		}
+		next -= segment
+	}
+	if last := points[len(points)-1]; samples[len(samples)-1] != last {
+		samples = append(samples, last)
+	}
+	return samples
+}
+
+// DistanceToPolyline returns the distance in meters from l to the closest point on the path.
+// It returns +Inf for an empty path
+func DistanceToPolyline(l Location, points []Location) float64 {
+	if len(points) == 1 {
+		return distanceMeters(l, points[0])
+	}
+	distance := math.Inf(1)
+	for i := 1; i < len(points); i++ {
+		distance = min(distance, geometry.DistanceToSegment(l.Point(), points[i-1].Point(), points[i].Point()))
+	}
+	return distance
+}
@@ -0,0 +1,79 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/geolocate/geo/geometry"
+	"github.com/stretchr/testify/assert"
+)
+
+// Example from Google's polyline algorithm documentation
+var polylineExample = []Location{
+	{Latitude: 38.5, Longitude: -120.2},
+	{Latitude: 40.7, Longitude: -120.95},
+	{Latitude: 43.252, Longitude: -126.453},
+}
+
+// Testing encoding against the documented example and round trips at both precisions
+func Test_EncodePolyline(t *testing.T) {
+	encoded := EncodePolyline(polylineExample, PolylinePrecision)
+	assert.Equal(t, "_p~iF~ps|U_ulLnnqC_mqNvxq`@", encoded)
+	assert.Equal(t, encoded, NewPolyline(polylineExample).EncodedPolyline)
+
+	decoded, err := DecodePolyline(encoded, PolylinePrecision)
+	assert.NoError(t, err)
+	assert.Equal(t, polylineExample, decoded)
+
+	precise := []Location{{Latitude: 42.3601234, Longitude: -71.0589876}, {Latitude: -33.8688197, Longitude: 151.2092955}}
+	decoded, err = DecodePolyline(EncodePolyline(precise, 6), 6)
+	assert.NoError(t, err)
+	for i := range precise {
+		assert.InDelta(t, precise[i].Latitude, decoded[i].Latitude, 1e-6)
+		assert.InDelta(t, precise[i].Longitude, decoded[i].Longitude, 1e-6)
+	}
+	empty, err := NewPolyline(nil).Locations()
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+// Testing truncated and malformed encodings are rejected
+func Test_DecodePolyline_Invalid(t *testing.T) {
+	for _, encoded := range []string{"_p~iF", "_p~iF~ps|", "_p~iF ps|U", "\x7f"} {
+		_, err := DecodePolyline(encoded, PolylinePrecision)
+		assert.ErrorIs(t, err, ErrInvalidPolyline, encoded)
+	}
+}
+
+// Testing length, simplification, sampling and distance to a path
+func Test_PolylineGeometry(t *testing.T) {
+	// A path east along the equator with a 1 meter wobble and a 1km detour north
+	path := []Location{
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 0.000009, Longitude: 0.01},
+		{Latitude: 0, Longitude: 0.02},
+		{Latitude: 0.009, Longitude: 0.03},
+		{Latitude: 0, Longitude: 0.04},
+	}
+	length := PolylineLength(path)
+	assert.InDelta(t, 5216, length, 1) // 2 km east and two 1.4 km legs around the detour
+
+	simplified := SimplifyPolyline(path, 5)
+	assert.Equal(t, []Location{path[0], path[2], path[3], path[4]}, simplified)
+	assert.Equal(t, []Location{path[0], path[4]}, SimplifyPolyline(path, 2000))
+	assert.Equal(t, path[:2], SimplifyPolyline(path[:2], 5))
+
+	samples := SamplePolyline(path, 500)
+	assert.Len(t, samples, 12) // every 500m along 5.2km plus the end
+	assert.Equal(t, path[0], samples[0])
+	assert.Equal(t, path[4], samples[len(samples)-1])
+	for i := 1; i < len(samples)-1; i++ {
+		assert.InDelta(t, 0, DistanceToPolyline(samples[i], path), 0.01)
+		assert.LessOrEqual(t, distanceMeters(samples[i-1], samples[i]), 500.01) // shorter when cutting a corner
+	}
+
+	assert.InDelta(t, 1112, DistanceToPolyline(Location{Latitude: -0.01, Longitude: 0.005}, path), 2)
+	assert.InDelta(t, 1112, DistanceToPolyline(Location{Latitude: 0, Longitude: -0.01}, path), 2) // before the start
+	assert.InDelta(t, 1112, DistanceToPolyline(Location{Latitude: 0, Longitude: 0.05}, path), 2)  // past the end
+	assert.InDelta(t, 0, DistanceToPolyline(path[3], path), 1e-6)
+	assert.InDelta(t, geometry.Haversine(geometry.Point{}, geometry.Point{Lat: 1}), DistanceToPolyline(Location{Latitude: 1}, path[:1]), 1e-6)
+}
@@ -0,0 +1,269 @@
+package geo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Maximum edit distance accepted when correcting a long misspelt type, and the number of
+// suggestions returned to the user when a type could not be resolved.
+const (
+	maxTypeEditDistance = 2
+	maxTypeSuggestions  = 3
+)
+
+// Free form words users type mapped to the PlaceTypes they most likely mean
+var placeTypeSynonyms = map[string][]PlaceType{
+	"acai":         {AcaiShop},
+	"afghan":       {AfghaniRestaurant},
+	"bagel":        {BagelShop},
+	"bbq":          {BarbecueRestaurant},
+	"barbeque":     {BarbecueRestaurant},
+	"bistro":       {Restaurant, Cafe},
+	"burger":       {HamburgerRestaurant},
+	"hamburger":    {HamburgerRestaurant},
+	"candy":        {CandyStore},
+	"chocolate":    {ChocolateShop, ChocolateFactory},
+	"club":         {Club},
+	"nightclub":    {Club},
+	"coffee":       {CoffeeShop, Cafe},
+	"espresso":     {CoffeeShop},
+	"deli":         {Deli},
+	"delicatessen": {Deli},
+	"delivery":     {MealDelivery},
+	"donut":        {DonutShop},
+	"doughnut":     {DonutShop},
+	"fast_food":    {FastFoodRestaurant},
+	"fine_dining":  {FineDiningRestaurant},
+	"gelato":       {IceCreamShop},
+	"ice_cream":    {IceCreamShop},
+	"juice":        {JuiceShop},
+	"smoothie":     {JuiceShop},
+	"pizza":        {PizzaRestaurant},
+	"pizzeria":     {PizzaRestaurant},
+	"ramen":        {RamenRestaurant},
+	"noodle":       {RamenRestaurant},
+	"sandwich":     {SandwichShop},
+	"sub":          {SandwichShop},
+	"seafood":      {SeafoodRestaurant},
+	"fish":         {SeafoodRestaurant},
+	"steak":        {SteakHouse},
+	"steakhouse":   {SteakHouse},
+	"sushi":        {SushiRestaurant},
+	"takeaway":     {MealTakeaway},
+	"takeout":      {MealTakeaway},
+	"tea":          {TeaHouse},
+	"teahouse":     {TeaHouse},
+	"vegan":        {VeganRestaurant},
+	"vegetarian":   {VegetarianRestaurant},
+	"veggie":       {VegetarianRestaurant, VeganRestaurant},
+	"wine":         {WineBar},
+}
+
+// Broad categories that expand into a group of related PlaceTypes
+var placeTypeCategories = map[string][]PlaceType{
+	"food":      {Restaurant, FastFoodRestaurant, Cafe, Diner, FoodCourt},
+	"drinks":    {Bar, Pub, WineBar, BarAndGrill, Club},
+	"nightlife": {Bar, Pub, WineBar, Club},
+	"dessert":   {DessertShop, DessertRestaurant, IceCreamShop, DonutShop, ChocolateShop, Confectionery, CandyStore},
+	"sweets":    {DessertShop, CandyStore, ChocolateShop, Confectionery},
+	"breakfast": {BreakfastRestaurant, BrunchRestaurant, BagelShop, Cafe, Diner},
+	"brunch":    {BrunchRestaurant, BreakfastRestaurant, Cafe},
+	"pet_cafe":  {CatCafe, DogCafe},
+}
+
+// UnknownPlaceType reports a user supplied type that could not be mapped to any
+// PlaceType along with the closest known alternatives.
+type UnknownPlaceType struct {
+	Input       string      `json:"input"`
+	Suggestions []PlaceType `json:"suggestions"`
+}
+
+func (u UnknownPlaceType) Error() string {
+	return fmt.Sprintf("maps: unknown place type %q", u.Input)
+}
+
+// ResolvePlaceTypes maps free form user input ("sushi", "coffee", "night club", "bars")
+// to valid PlaceTypes. Inputs are normalised, singularised and matched against known types,
+// synonyms and categories, falling back to a close spelling match. Inputs that cannot be
+// resolved are returned in unknown with suggestions. Resolved types are deduplicated.
+func ResolvePlaceTypes(inputs []string) (resolved []PlaceType, unknown []UnknownPlaceType) {
+	seen := map[PlaceType]bool{}
+	for _, input := range inputs {
+		types, err := ResolvePlaceType(input)
+		if err != nil {
+			unknown = append(unknown, err.(UnknownPlaceType))
+			continue
+		}
+		for _, t := range types {
+			if !seen[t] {
+				seen[t] = true
+				resolved = append(resolved, t)
+			}
+		}
+	}
+	return resolved, unknown
+}
+
+// ResolvePlaceType maps a single free form input to one or more PlaceTypes. It returns an
+// UnknownPlaceType error carrying suggestions when no match is found.
+func ResolvePlaceType(input string) ([]PlaceType, error) {
+	key := normalizeTypeInput(input)
+	if key == "" {
+		return nil, UnknownPlaceType{Input: input}
+	}
+	for _, candidate := range typeKeyVariants(key) {
+		if types := lookupTypeKey(candidate); len(types) > 0 {
+			return types, nil
+		}
+	}
+	// Nothing matched verbatim. Accept a spelling correction only if it is unambiguous
+	matches := closestTypeKeys(key)
+	if len(matches) > 0 && correctable(key, matches[0]) &&
+		(len(matches) == 1 || matches[1].distance > matches[0].distance) {
+		return lookupTypeKey(matches[0].key), nil
+	}
+	return nil, UnknownPlaceType{Input: input, Suggestions: typeSuggestions(matches)}
+}
+
+// Whether key is close enough to match to be a misspelling of it. Short words are often other words a
+// letter away (car and bar, cake and cafe), so they are only corrected for a dropped or doubled letter,
+// and longer words by at most a third of their letters
+func correctable(key string, match typeKeyMatch) bool {
+	switch n := len(key); {
+	case n <= 3:
+		return false
+	case n <= 5:
+		return match.distance == 1 && len(match.key) != n
+	default:
+		return match.distance <= min(maxTypeEditDistance, n/3)
+	}
+}
+
+// Lowercase the input and join words with '_' the way PlaceType values are written
+func normalizeTypeInput(input string) string {
+	fields := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9')
+	})
+	return strings.Join(fields, "_")
+}
+
+// Candidate keys for an input: as typed, singular forms, then with common type suffixes
+func typeKeyVariants(key string) []string {
+	variants := append([]string{key}, singularForms(key)...)
+	base := variants
+	for _, suffix := range []string{"_restaurant", "_shop", "_store"} {
+		for _, v := range base {
+			variants = append(variants, v+suffix)
+		}
+	}
+	return variants
+}
+
+// Singular forms of the last word of key. "bakeries" -> "bakery", "bars" -> "bar", "sandwiches" -> "sandwich"
+func singularForms(key string) []string {
+	var forms []string
+	switch {
+	case strings.HasSuffix(key, "ies"):
+		forms = append(forms, strings.TrimSuffix(key, "ies")+"y")
+	case strings.HasSuffix(key, "ches"), strings.HasSuffix(key, "shes"), strings.HasSuffix(key, "xes"):
+		forms = append(forms, strings.TrimSuffix(key, "es"))
+	}
+	if strings.HasSuffix(key, "s") && !strings.HasSuffix(key, "ss") {
+		forms = append(forms, strings.TrimSuffix(key, "s"))
+	}
+	return forms
+}
+
+func lookupTypeKey(key string) []PlaceType {
+	if t, ok := knownPlaceTypes()[key]; ok {
+		return []PlaceType{t}
+	}
+	if types, ok := placeTypeSynonyms[key]; ok {
+		return types
+	}
+	if types, ok := placeTypeCategories[key]; ok {
+		return types
+	}
+	// Multi word inputs typed apart from a one word synonym ("tea house" -> "teahouse")
+	if types, ok := placeTypeSynonyms[strings.ReplaceAll(key, "_", "")]; ok {
+		return types
+	}
+	return nil
+}
+
+// Every PlaceType the resolver can return keyed by its string value
+func knownPlaceTypes() map[string]PlaceType {
+	known := map[string]PlaceType{string(Club): Club}
+	for _, t := range GetAllPlacesTypes() {
+		known[string(t)] = t
+	}
+	return known
+}
+
+type typeKeyMatch struct {
+	key      string
+	distance int
+}
+
+// Known keys ordered by edit distance to key. Ties keep alphabetical order.
+func closestTypeKeys(key string) []typeKeyMatch {
+	var keys []string
+	for k := range knownPlaceTypes() {
+		keys = append(keys, k)
+	}
+	for k := range placeTypeSynonyms {
+		keys = append(keys, k)
+	}
+	for k := range placeTypeCategories {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	matches := make([]typeKeyMatch, 0, len(keys))
+	for _, k := range keys {
+		matches = append(matches, typeKeyMatch{key: k, distance: editDistance(key, k)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+	return matches
+}
+
+// The first few distinct PlaceTypes reachable from the closest keys
+func typeSuggestions(matches []typeKeyMatch) []PlaceType {
+	var suggestions []PlaceType
+	seen := map[PlaceType]bool{}
+	for _, m := range matches {
+		for _, t := range lookupTypeKey(m.key) {
+			if seen[t] {
+				continue
+			}
+			seen[t] = true
+			suggestions = append(suggestions, t)
+			if len(suggestions) == maxTypeSuggestions {
+				return suggestions
+			}
+		}
+	}
+	return suggestions
+}
+
+// Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
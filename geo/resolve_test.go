@@ -0,0 +1,68 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing free form user input resolves to the expected place types
+func Test_ResolvePlaceType(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []PlaceType
+	}{
+		{"restaurant", []PlaceType{Restaurant}},
+		{"Sushi", []PlaceType{SushiRestaurant}},
+		{"coffee", []PlaceType{CoffeeShop, Cafe}},
+		{"night club", []PlaceType{Club}},
+		{"bars", []PlaceType{Bar}},
+		{"bakeries", []PlaceType{Bakery}},
+		{"sandwiches", []PlaceType{SandwichShop}},
+		{"italian", []PlaceType{ItalianRestaurant}},
+		{"Tea House", []PlaceType{TeaHouse}},
+		{"nightlife", []PlaceType{Bar, Pub, WineBar, Club}},
+		{"resturant", []PlaceType{Restaurant}},
+		{"piza", []PlaceType{PizzaRestaurant}},
+	}
+	for _, tt := range tests {
+		got, err := ResolvePlaceType(tt.input)
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+}
+
+// Testing unknown input is rejected with suggestions
+func Test_ResolvePlaceType_Unknown(t *testing.T) {
+	_, err := ResolvePlaceType("bowling arena")
+	assert.Error(t, err)
+	unknown, ok := err.(UnknownPlaceType)
+	assert.True(t, ok)
+	assert.Equal(t, "bowling arena", unknown.Input)
+	assert.NotEmpty(t, unknown.Suggestions)
+
+	_, err = ResolvePlaceType("  ")
+	assert.Error(t, err)
+}
+
+// Testing short words a letter or two from a type are suggested, not silently corrected
+func Test_ResolvePlaceType_ShortWords(t *testing.T) {
+	for _, input := range []string{"car", "art", "park", "bank", "gas", "pho", "sun", "cake"} {
+		got, err := ResolvePlaceType(input)
+		assert.Nil(t, got, input)
+		unknown, ok := err.(UnknownPlaceType)
+		assert.True(t, ok, input)
+		assert.NotEmpty(t, unknown.Suggestions, input)
+	}
+	got, err := ResolvePlaceType("pubb")
+	assert.NoError(t, err)
+	assert.Equal(t, []PlaceType{Pub}, got)
+}
+
+// Testing multiple inputs are deduplicated and unknowns collected
+func Test_ResolvePlaceTypes(t *testing.T) {
+	resolved, unknown := ResolvePlaceTypes([]string{"pubs", "pub", "coffee", "zzzzzzzz"})
+	assert.Equal(t, []PlaceType{Pub, CoffeeShop, Cafe}, resolved)
+	assert.Len(t, unknown, 1)
+	assert.Equal(t, "zzzzzzzz", unknown[0].Input)
+}
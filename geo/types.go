@@ -32,6 +32,21 @@ type OpeningHours struct {
 }
 type SecondaryHoursType string
 
+// Types of secondary opening hours a place can report alongside its main hours
+const (
+	SecondaryHoursTypeDriveThrough = SecondaryHoursType("DRIVE_THROUGH")
+	SecondaryHoursTypeHappyHour    = SecondaryHoursType("HAPPY_HOUR")
+	SecondaryHoursTypeDelivery     = SecondaryHoursType("DELIVERY")
+	SecondaryHoursTypeTakeout      = SecondaryHoursType("TAKEOUT")
+	SecondaryHoursTypeKitchen      = SecondaryHoursType("KITCHEN")
+	SecondaryHoursTypeBreakfast    = SecondaryHoursType("BREAKFAST")
+	SecondaryHoursTypeLunch        = SecondaryHoursType("LUNCH")
+	SecondaryHoursTypeDinner       = SecondaryHoursType("DINNER")
+	SecondaryHoursTypeBrunch       = SecondaryHoursType("BRUNCH")
+	SecondaryHoursTypePickup       = SecondaryHoursType("PICKUP")
+	SecondaryHoursTypeSeniorHours  = SecondaryHoursType("SENIOR_HOURS")
+)
+
 type SpecialDay struct {
 	Date Date `json:"date"`
 }
@@ -54,8 +69,8 @@ type Timezone struct {
 }
 
 type Period struct {
-	Open  Point `json:"open"`
-	Close Point `json:"close"`
+	Open  Point  `json:"open"`
+	Close *Point `json:"close,omitempty"` // Missing for places open 24 hours, every day
 }
 type Point struct {
 	Date      Date `json:"date"`
@@ -0,0 +1,90 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Largest span in degrees of latitude or longitude sent as one rectangular restriction. Larger
+// viewports, such as a state or country, are split into a grid of rectangles searched separately
+const maxRestrictionSpan = 2.0
+
+// Most rectangles a viewport is split into before it is rejected as too large to search
+const maxRestrictionRectangles = 16
+
+// RestrictionRectangles converts a viewport into rectangles the Places API accepts as a location
+// restriction. Viewports crossing the antimeridian are split in two and large ones into a grid.
+func RestrictionRectangles(viewport LatLngBounds) ([]Rectangle, error) {
+	b := viewport.Geometry()
+	if b.NE.Lat <= b.SW.Lat || b.LngSpan() == 0 {
+		return nil, errors.New("maps: viewport has no area")
+	}
+	var rects []Rectangle
+	for _, part := range b.Split() {
+		latSpan, lngSpan := part.NE.Lat-part.SW.Lat, part.LngSpan()
+		rows, cols := int(math.Ceil(latSpan/maxRestrictionSpan)), int(math.Ceil(lngSpan/maxRestrictionSpan))
+		if len(rects)+rows*cols > maxRestrictionRectangles {
+			return nil, fmt.Errorf("maps: viewport is too large to search, it needs more than %d rectangles", maxRestrictionRectangles)
+		}
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				rects = append(rects, Rectangle{
+					Low:  Location{Latitude: part.SW.Lat + latSpan*float64(i)/float64(rows), Longitude: part.SW.Lng + lngSpan*float64(j)/float64(cols)},
+					High: Location{Latitude: part.SW.Lat + latSpan*float64(i+1)/float64(rows), Longitude: part.SW.Lng + lngSpan*float64(j+1)/float64(cols)},
+				})
+			}
+		}
+	}
+	return rects, nil
+}
+
+// TextSearchInViewport runs a text search restricted to a viewport, such as a geocoded city, and
+// returns only places inside it. A viewport split into several rectangles is searched once per
+// rectangle and results are deduplicated by place ID; paging is then not available and r's PageToken
+// is ignored. r's LocationBias and LocationRestriction are replaced.
+func (c *GeoClient) TextSearchInViewport(ctx context.Context, viewport LatLngBounds, r *TextSearchRequest, h *PlacesHeader) (PlacesSearchResponse, error) {
+	return c.textSearchInBounds(ctx, viewport, viewport.Rectangle(), r, h)
+}
+
+// Search the rectangles covering bounds and keep the places inside area
+func (c *GeoClient) textSearchInBounds(ctx context.Context, bounds LatLngBounds, area SearchArea, r *TextSearchRequest, h *PlacesHeader) (PlacesSearchResponse, error) {
+	rects, err := RestrictionRectangles(bounds)
+	if err != nil {
+		return PlacesSearchResponse{}, err
+	}
+	header := PlacesHeader{FieldMaskPrefix: true}
+	if h != nil {
+		header = *h
+	}
+	// Place ID deduplicates results across rectangles and location filters them to the area
+	header.FieldMasks = withFieldMask(withFieldMask(header.FieldMasks, PlaceFieldMaskPlaceID), PlaceFieldMaskLocation)
+	if len(rects) > 1 {
+		header.TokenMask = ""
+	}
+	var resp PlacesSearchResponse
+	seen := map[string]bool{}
+	for _, rect := range rects {
+		req := *r
+		req.LocationBias = nil
+		req.LocationRestriction = &RectangularRestriction{Rectangle: rect}
+		if len(rects) > 1 {
+			req.PageToken = ""
+		}
+		page, err := c.TextSearch(ctx, &req, &header)
+		if err != nil {
+			return PlacesSearchResponse{}, err
+		}
+		for _, place := range FilterPlaces(page.Places, area) {
+			if !seen[place.Id] {
+				seen[place.Id] = true
+				resp.Places = append(resp.Places, place)
+			}
+		}
+		if len(rects) == 1 {
+			resp.NextPageToken = page.NextPageToken
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,65 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geolocate/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing viewports are converted to rectangles, split across the antimeridian and when large
+func Test_RestrictionRectangles(t *testing.T) {
+	halifax := LatLngBounds{SouthWest: LatLng{Lat: 44.581167, Lng: -63.72259}, NorthEast: LatLng{Lat: 44.711211, Lng: -63.54319}}
+	rects, err := RestrictionRectangles(halifax)
+	assert.NoError(t, err)
+	assert.Equal(t, []Rectangle{halifax.Rectangle()}, rects)
+
+	fiji := LatLngBounds{SouthWest: LatLng{Lat: -19, Lng: 177}, NorthEast: LatLng{Lat: -16, Lng: -179}}
+	rects, err = RestrictionRectangles(fiji)
+	assert.NoError(t, err)
+	assert.Len(t, rects, 6) // 3 degrees tall in two rows. 3 degrees west of the antimeridian in two columns and 1 east in one
+	for _, r := range rects {
+		assert.LessOrEqual(t, r.Low.Longitude, r.High.Longitude)
+		assert.LessOrEqual(t, r.High.Latitude-r.Low.Latitude, maxRestrictionSpan)
+		assert.True(t, fiji.Rectangle().Contains(r.center()))
+	}
+
+	_, err = RestrictionRectangles(LatLngBounds{SouthWest: LatLng{Lat: 44, Lng: -63}, NorthEast: LatLng{Lat: 44, Lng: -63}})
+	assert.Error(t, err)
+	_, err = RestrictionRectangles(LatLngBounds{SouthWest: LatLng{Lat: 24, Lng: -125}, NorthEast: LatLng{Lat: 49, Lng: -66}})
+	assert.Error(t, err) // the continental US is too large
+}
+
+// Testing a split viewport is searched once per rectangle and results outside it are dropped
+func Test_TextSearchInViewport(t *testing.T) {
+	var restrictions []Rectangle
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Nil(t, req.LocationBias)
+		assert.Empty(t, req.PageToken)
+		assert.Contains(t, r.Header.Get("X-Goog-FieldMask"), "places.location")
+		restrictions = append(restrictions, req.LocationRestriction.Rectangle)
+		json.NewEncoder(w).Encode(PlacesSearchResponse{Places: []Place{
+			{Id: "inside", Location: Location{Latitude: -17.7, Longitude: 179.9}},
+			{Id: "outside", Location: Location{Latitude: -15, Longitude: 179.9}},
+		}, NextPageToken: "token"})
+	}))
+	defer srv.Close()
+	c, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(srv.URL), client.WithRateLimit(0))
+	assert.NoError(t, err)
+	geoClient := GeoClient{c}
+
+	fiji := LatLngBounds{SouthWest: LatLng{Lat: -18, Lng: 179}, NorthEast: LatLng{Lat: -17, Lng: -179.5}}
+	req := TextSearchRequest{TextQuery: "resort", PageToken: "stale", LocationBias: &LocationRestriction{}}
+	resp, err := geoClient.TextSearchInViewport(context.Background(), fiji, &req, nil)
+	assert.NoError(t, err)
+	assert.Len(t, restrictions, 2)
+	assert.Len(t, resp.Places, 1)
+	assert.Equal(t, "inside", resp.Places[0].Id)
+	assert.Empty(t, resp.NextPageToken) // no paging across rectangles
+}
@@ -0,0 +1,73 @@
+// Package testutil holds the fakes shared by the tests of the store and watch packages.
+package testutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/geolocate/client"
+	"github.com/geolocate/geo"
+)
+
+// Clock only moves when waited on or advanced, recording the waits
+type Clock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// Advance moves the clock without recording a wait
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Waits returns the durations waited on so far
+func (c *Clock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration{}, c.waits...)
+}
+
+// Fetcher answers details lookups from Places. Missing IDs are NOT_FOUND, and every lookup fails with Err when set
+type Fetcher struct {
+	Places map[string]geo.Place
+	Err    error
+	Calls  []string               // IDs looked up, in order
+	Masks  [][]geo.PlaceFieldMask // Field masks of the lookups, in order
+}
+
+func (f *Fetcher) PlaceDetails(ctx context.Context, id string, h *geo.PlacesHeader) (geo.Place, error) {
+	f.Calls = append(f.Calls, id)
+	f.Masks = append(f.Masks, h.FieldMasks)
+	if f.Err != nil {
+		return geo.Place{}, f.Err
+	}
+	p, ok := f.Places[id]
+	if !ok {
+		return geo.Place{}, client.HttpError{Status: 404}
+	}
+	return p, nil
+}
@@ -0,0 +1,211 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/geolocate/geo"
+)
+
+// Format of a job's input, and of its output
+type Format string
+
+const (
+	FormatJSONL = Format("jsonl")
+	FormatCSV   = Format("csv")
+)
+
+// MaxRows is the most rows a job may hold
+const MaxRows = 100000
+
+var ErrTooManyRows = fmt.Errorf("jobs: more than %d rows", MaxRows)
+
+// Row is one address to geocode or location to reverse geocode
+type Row struct {
+	Index   int         // Position in the input, from 0
+	ID      string      // Caller's ID for the row, copied to its result
+	Address string      // Geocoded when set
+	LatLng  *geo.LatLng // Reverse geocoded when Address is empty
+	Error   string      // Why the row could not be read. It is reported in the output instead of geocoded
+}
+
+// A JSONL input line. Eg: {"id": "1", "address": "1600 Amphitheatre Pkwy"} or {"id": "2", "latitude": 40.7, "longitude": -73.9}
+type jsonRow struct {
+	ID        string   `json:"id"`
+	Address   string   `json:"address"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// DetectFormat picks the format of input from its content type, or from its first byte when that says nothing
+func DetectFormat(contentType string, peek []byte) (Format, error) {
+	switch {
+	case strings.Contains(contentType, "csv"):
+		return FormatCSV, nil
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonl"), strings.Contains(contentType, "json"):
+		return FormatJSONL, nil
+	}
+	peek = bytes.TrimSpace(bytes.TrimPrefix(peek, []byte("\ufeff")))
+	if len(peek) == 0 {
+		return "", errors.New("jobs: empty input")
+	}
+	if peek[0] == '{' {
+		return FormatJSONL, nil
+	}
+	return FormatCSV, nil
+}
+
+// readRows calls f for every row of r. Rows that cannot be read are passed with Error set; only input
+// that cannot be read at all, such as CSV without an address or latitude/longitude column, is an error
+func readRows(r io.Reader, format Format, f func(Row) error) error {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r, f)
+	case FormatCSV:
+		return readCSV(r, f)
+	}
+	return fmt.Errorf("jobs: unknown format %q", format)
+}
+
+func readJSONL(r io.Reader, f func(Row) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	index := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(bytes.TrimPrefix(scanner.Bytes(), []byte("\ufeff")))
+		if len(line) == 0 {
+			continue
+		}
+		row := Row{Index: index}
+		var in jsonRow
+		if err := json.Unmarshal(line, &in); err != nil {
+			row.Error = err.Error()
+		} else {
+			row.ID = in.ID
+			row.setInput(in.Address, in.Latitude, in.Longitude)
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+		index++
+	}
+	return scanner.Err()
+}
+
+// CSV columns, matched ignoring case. The first row must name them
+var (
+	idColumns        = []string{"id"}
+	addressColumns   = []string{"address"}
+	latitudeColumns  = []string{"latitude", "lat"}
+	longitudeColumns = []string{"longitude", "lng", "lon", "long"}
+)
+
+func readCSV(r io.Reader, f func(Row) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return errors.New("jobs: empty input")
+	}
+	if err != nil {
+		return err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // byte order mark written by spreadsheets
+	}
+	id, address := column(header, idColumns), column(header, addressColumns)
+	lat, lng := column(header, latitudeColumns), column(header, longitudeColumns)
+	if address < 0 && (lat < 0 || lng < 0) {
+		return errors.New("jobs: CSV needs an address column or latitude and longitude columns")
+	}
+	for index := 0; ; index++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		row := Row{Index: index}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			row.Error = err.Error()
+		case err != nil:
+			return err
+		default:
+			row.ID = field(record, id)
+			latitude, err := parseCoordinate(field(record, lat))
+			if err != nil {
+				row.Error = "latitude: " + err.Error()
+				break
+			}
+			longitude, err := parseCoordinate(field(record, lng))
+			if err != nil {
+				row.Error = "longitude: " + err.Error()
+				break
+			}
+			row.setInput(field(record, address), latitude, longitude)
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+}
+
+// Set the address, or the location when there is no address, flagging rows with neither
+func (row *Row) setInput(address string, lat, lng *float64) {
+	row.Address = strings.TrimSpace(address)
+	switch {
+	case row.Address != "":
+	case lat == nil || lng == nil:
+		row.Error = "row has no address or latitude and longitude"
+	case *lat < -90 || *lat > 90 || *lng < -180 || *lng > 180:
+		row.Error = "latitude or longitude out of range"
+	default:
+		row.LatLng = &geo.LatLng{Lat: *lat, Lng: *lng}
+	}
+}
+
+// Input as written in the output. Eg: "44.6488,-63.5752"
+func (row Row) input() string {
+	if row.LatLng != nil {
+		return strconv.FormatFloat(row.LatLng.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(row.LatLng.Lng, 'f', -1, 64)
+	}
+	return row.Address
+}
+
+func column(header []string, names []string) int {
+	for i, h := range header {
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// Empty coordinates are nil, not an error, so rows may have either an address or a location
+func parseCoordinate(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
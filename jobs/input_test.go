@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func collect(t *testing.T, input string, format Format) []Row {
+	var rows []Row
+	assert.NoError(t, readRows(strings.NewReader(input), format, func(r Row) error {
+		rows = append(rows, r)
+		return nil
+	}))
+	return rows
+}
+
+// Testing JSONL lines become rows, with unreadable lines reported on their row
+func Test_ReadJSONL(t *testing.T) {
+	rows := collect(t, `{"id": "a", "address": "1 Main St"}
+
+{"id": "b", "latitude": 44.6488, "longitude": -63.5752}
+{"id": "c"}
+not json
+`, FormatJSONL)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, Row{Index: 0, ID: "a", Address: "1 Main St"}, rows[0])
+	assert.Equal(t, Row{Index: 1, ID: "b", LatLng: &geo.LatLng{Lat: 44.6488, Lng: -63.5752}}, rows[1])
+	assert.Equal(t, "44.6488,-63.5752", rows[1].input())
+	assert.NotEmpty(t, rows[2].Error)
+	assert.Equal(t, 3, rows[3].Index)
+	assert.NotEmpty(t, rows[3].Error)
+}
+
+// Testing CSV columns are found by name, ignoring case and a byte order mark
+func Test_ReadCSV(t *testing.T) {
+	rows := collect(t, "\ufeffID,Address,Lat,Lng\na,\"1 Main St, Halifax\",,\nb,,44.6488,-63.5752\nc,,north,-63\nd,,91,0\n", FormatCSV)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, Row{Index: 0, ID: "a", Address: "1 Main St, Halifax"}, rows[0])
+	assert.Equal(t, &geo.LatLng{Lat: 44.6488, Lng: -63.5752}, rows[1].LatLng)
+	assert.Contains(t, rows[2].Error, "latitude")
+	assert.Equal(t, "latitude or longitude out of range", rows[3].Error)
+
+	err := readRows(strings.NewReader("name,city\nx,y\n"), FormatCSV, func(Row) error { return nil })
+	assert.Error(t, err)
+}
+
+// Testing the format is taken from the content type, then the content
+func Test_DetectFormat(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		peek        string
+		format      Format
+	}{
+		{"text/csv", "{", FormatCSV},
+		{"application/x-ndjson", "id", FormatJSONL},
+		{"", "  {\"id\": 1}", FormatJSONL},
+		{"application/octet-stream", "address\n", FormatCSV},
+	} {
+		format, err := DetectFormat(tc.contentType, []byte(tc.peek))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.format, format, tc)
+	}
+	_, err := DetectFormat("", []byte(" \n"))
+	assert.Error(t, err)
+}
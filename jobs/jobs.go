@@ -0,0 +1,430 @@
+// Package jobs runs batch geocoding jobs. A job is a JSONL or CSV file of addresses to geocode or
+// locations to reverse geocode. Rows are looked up by a fixed number of workers sharing the client's
+// rate limiter, and every result is appended to a checkpoint file as soon as it is known, so a job
+// interrupted by a crash or restart resumes where it stopped.
+//
+// Each job lives in its own directory:
+//
+//	job.json       status and progress
+//	input.<fmt>    the submitted file
+//	results.jsonl  the checkpoint, one result per line in completion order
+//	output.<fmt>   every result in input order, written when the job is done
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/geolocate/geo"
+)
+
+// Status of a job
+type Status string
+
+const (
+	StatusQueued  = Status("queued")
+	StatusRunning = Status("running")
+	StatusDone    = Status("done")
+	StatusFailed  = Status("failed") // The job could not run. Rows that fail are reported in the output instead
+)
+
+// Progress is saved to job.json every this many rows. The checkpoint itself is written for every row
+const saveEvery = 100
+
+var (
+	ErrNotFound = errors.New("jobs: job not found")
+	ErrNotDone  = errors.New("jobs: job is not done")
+)
+
+// Geocoder looks up addresses and locations. geo.GeoClient implements it
+type Geocoder interface {
+	Geocode(ctx context.Context, r *geo.GeocodingRequest) (geo.GeocodingResponse, error)
+	Geodecode(ctx context.Context, r *geo.GeocodingRequest) (geo.GeocodingResponse, error)
+}
+
+// Job is a batch of rows and its progress
+type Job struct {
+	ID         string     `json:"id"`
+	Format     Format     `json:"format"`
+	Status     Status     `json:"status"`
+	Total      int        `json:"total"`     // Rows in the input
+	Processed  int        `json:"processed"` // Rows with a result, failed ones included
+	Failed     int        `json:"failed"`    // Rows whose result is an error
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Manager runs jobs stored under a directory. It is safe for concurrent use
+type Manager struct {
+	dir         string
+	geocoder    Geocoder
+	concurrency int
+	logger      *log.Logger
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewManager loads the jobs under dir and resumes the ones that did not finish.
+// concurrency is the number of rows looked up at once, defaulting to 4. Failures are reported to logger,
+// or the standard logger when it is nil
+func NewManager(dir string, geocoder Geocoder, concurrency int, logger *log.Logger) (*Manager, error) {
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{dir: dir, geocoder: geocoder, concurrency: concurrency, logger: logger, ctx: ctx, cancel: cancel, jobs: map[string]*Job{}}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), "job.json"))
+		if err != nil {
+			continue // not a job, or one whose submission did not complete
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil || job.ID != e.Name() {
+			continue
+		}
+		m.jobs[job.ID] = &job
+		if job.Status == StatusQueued || job.Status == StatusRunning {
+			m.start(job.ID)
+		}
+	}
+	return m, nil
+}
+
+// Close stops running jobs, leaving them to resume when the directory is opened again
+func (m *Manager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Submit saves input as a new job and starts it. The input is read through once to count its rows,
+// so malformed files are rejected here rather than failing later
+func (m *Manager) Submit(input io.Reader, format Format) (Job, error) {
+	id := newID()
+	dir := filepath.Join(m.dir, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Job{}, err
+	}
+	inputPath := filepath.Join(dir, "input."+string(format))
+	f, err := os.Create(inputPath)
+	if err != nil {
+		return Job{}, err
+	}
+	_, err = io.Copy(f, input)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	total := 0
+	if err == nil {
+		total, err = countRows(inputPath, format)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return Job{}, err
+	}
+	job := &Job{ID: id, Format: format, Status: StatusQueued, Total: total, CreatedAt: time.Now()}
+	m.mu.Lock()
+	m.jobs[id] = job
+	err = m.save(job)
+	snapshot := *job
+	m.mu.Unlock()
+	if err != nil {
+		return Job{}, err
+	}
+	m.start(id)
+	return snapshot, nil
+}
+
+// Get returns a job's progress
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns every job, newest first
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// Output opens a finished job's output, which is in the format of its input
+func (m *Manager) Output(id string) (io.ReadCloser, Format, error) {
+	job, ok := m.Get(id)
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	if job.Status != StatusDone {
+		return nil, "", ErrNotDone
+	}
+	f, err := os.Open(filepath.Join(m.dir, id, "output."+string(job.Format)))
+	return f, job.Format, err
+}
+
+func (m *Manager) start(id string) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := m.run(id); err != nil && m.ctx.Err() == nil {
+			m.logger.Printf("jobs: %s failed, %s", id, err)
+			m.update(id, func(job *Job) {
+				job.Status, job.Error = StatusFailed, err.Error()
+				now := time.Now()
+				job.FinishedAt = &now
+			})
+		}
+	}()
+}
+
+// Look up every row without a checkpointed result, then write the output
+func (m *Manager) run(id string) error {
+	job, _ := m.Get(id)
+	dir := filepath.Join(m.dir, id)
+	checkpoint := filepath.Join(dir, "results.jsonl")
+	done, failed, err := loadCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+	m.update(id, func(job *Job) { job.Status, job.Processed, job.Failed = StatusRunning, len(done), failed })
+	out, err := os.OpenFile(checkpoint, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Stop feeding rows once a result cannot be checkpointed, rather than spend quota on lookups that are lost
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+	rows := make(chan Row)
+	results := make(chan Result)
+	var workers sync.WaitGroup
+	for i := 0; i < m.concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for row := range rows {
+				if r, ok := m.lookup(row); ok {
+					results <- r
+				}
+			}
+		}()
+	}
+	writeErr := make(chan error, 1)
+	go func() {
+		var err error
+		for r := range results {
+			if err != nil {
+				continue // drain so workers can finish
+			}
+			if err = writeResult(out, r); err != nil {
+				cancel()
+			} else {
+				m.record(id, r)
+			}
+		}
+		writeErr <- err
+	}()
+	readErr := readInput(filepath.Join(dir, "input."+string(job.Format)), job.Format, func(row Row) error {
+		if done[row.Index] {
+			return nil
+		}
+		select {
+		case rows <- row:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(rows)
+	workers.Wait()
+	close(results)
+	if err := <-writeErr; err != nil {
+		return err
+	}
+	if readErr != nil {
+		if m.ctx.Err() != nil {
+			m.update(id, func(*Job) {}) // save progress; the job resumes on the next start
+		}
+		return readErr
+	}
+	if err := writeOutput(checkpoint, filepath.Join(dir, "output."+string(job.Format)), job.Format); err != nil {
+		return err
+	}
+	m.update(id, func(job *Job) {
+		job.Status = StatusDone
+		now := time.Now()
+		job.FinishedAt = &now
+	})
+	return nil
+}
+
+// Geocode an address or reverse geocode a location, keeping the best match. Lookups cut short by
+// Close are not results; the row is looked up again when the job resumes
+func (m *Manager) lookup(row Row) (Result, bool) {
+	result := Result{Index: row.Index, ID: row.ID, Input: row.input(), Error: row.Error}
+	if row.Error != "" {
+		return result, true
+	}
+	var resp geo.GeocodingResponse
+	var err error
+	if row.Address != "" {
+		resp, err = m.geocoder.Geocode(m.ctx, &geo.GeocodingRequest{Address: row.Address})
+	} else {
+		resp, err = m.geocoder.Geodecode(m.ctx, &geo.GeocodingRequest{LatLng: row.LatLng})
+	}
+	switch {
+	case err != nil && m.ctx.Err() != nil:
+		return result, false
+	case err != nil:
+		result.Error = err.Error()
+	case len(resp.Results) == 0:
+		result.Error = "no results"
+	default:
+		best := resp.Results[0]
+		location := best.Geometry.Location
+		result.FormattedAddress, result.Location, result.PlaceID = best.FormattedAddress, &location, best.PlaceID
+	}
+	return result, true
+}
+
+// Count a result towards the job's progress, saving it every saveEvery rows
+func (m *Manager) record(id string, r Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	job.Processed++
+	if r.Error != "" {
+		job.Failed++
+	}
+	if job.Processed%saveEvery == 0 {
+		if err := m.save(job); err != nil {
+			m.logger.Printf("jobs: %s", err)
+		}
+	}
+}
+
+func (m *Manager) update(id string, f func(*Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	f(job)
+	if err := m.save(job); err != nil {
+		m.logger.Printf("jobs: %s", err)
+	}
+}
+
+// Write job.json, swapping it in with a rename so a crash leaves the old one intact
+func (m *Manager) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(m.dir, job.ID, "job.json")
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func readInput(path string, format Format, f func(Row) error) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return readRows(in, format, f)
+}
+
+func countRows(path string, format Format) (int, error) {
+	total := 0
+	err := readInput(path, format, func(Row) error {
+		if total++; total > MaxRows {
+			return ErrTooManyRows
+		}
+		return nil
+	})
+	if err == nil && total == 0 {
+		err = errors.New("jobs: input has no rows")
+	}
+	return total, err
+}
+
+// Rows with a result in the checkpoint, and how many of them failed. A line cut short by a crash is
+// truncated so later results are appended after the last complete one
+func loadCheckpoint(path string) (map[int]bool, int, error) {
+	done := map[int]bool{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+		data = data[:end]
+		if err := os.Truncate(path, int64(end)); err != nil {
+			return nil, 0, err
+		}
+	}
+	failed := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Result
+		if json.Unmarshal(scanner.Bytes(), &r) != nil || done[r.Index] {
+			continue
+		}
+		done[r.Index] = true
+		if r.Error != "" {
+			failed++
+		}
+	}
+	return done, failed, scanner.Err()
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// Geocoder answering from a map of addresses, recording what it was asked
+type fakeGeocoder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (g *fakeGeocoder) Geocode(ctx context.Context, r *geo.GeocodingRequest) (geo.GeocodingResponse, error) {
+	g.mu.Lock()
+	g.calls = append(g.calls, r.Address)
+	g.mu.Unlock()
+	if r.Address == "nowhere" {
+		return geo.GeocodingResponse{}, errors.New("maps: ZERO_RESULTS")
+	}
+	return geo.GeocodingResponse{Results: []geo.GeocodingResult{{
+		FormattedAddress: r.Address + ", Halifax", PlaceID: "id-" + r.Address,
+		Geometry: geo.AddressGeometry{Location: geo.LatLng{Lat: 44.6, Lng: -63.5}},
+	}}}, nil
+}
+
+func (g *fakeGeocoder) Geodecode(ctx context.Context, r *geo.GeocodingRequest) (geo.GeocodingResponse, error) {
+	g.mu.Lock()
+	g.calls = append(g.calls, "reverse")
+	g.mu.Unlock()
+	return geo.GeocodingResponse{Results: []geo.GeocodingResult{{FormattedAddress: "Spring Garden Rd", Geometry: geo.AddressGeometry{Location: *r.LatLng}}}}, nil
+}
+
+func waitDone(t *testing.T, m *Manager, id string) Job {
+	var job Job
+	assert.Eventually(t, func() bool {
+		job, _ = m.Get(id)
+		return job.Status == StatusDone || job.Status == StatusFailed
+	}, 5*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func readOutput(t *testing.T, m *Manager, id string) string {
+	out, _, err := m.Output(id)
+	assert.NoError(t, err)
+	defer out.Close()
+	data, _ := io.ReadAll(out)
+	return string(data)
+}
+
+// Testing a CSV job geocodes and reverse geocodes every row, writing errors on their row in input order
+func Test_Submit(t *testing.T) {
+	g := &fakeGeocoder{}
+	m, err := NewManager(t.TempDir(), g, 3, nil)
+	assert.NoError(t, err)
+	defer m.Close()
+
+	job, err := m.Submit(strings.NewReader("id,address,latitude,longitude\n1,1 Main St,,\n2,nowhere,,\n3,,44.64,-63.57\n4,,,\n"), FormatCSV)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, job.Total)
+	_, _, err = m.Output("missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	job = waitDone(t, m, job.ID)
+	assert.Equal(t, StatusDone, job.Status)
+	assert.Equal(t, 4, job.Processed)
+	assert.Equal(t, 2, job.Failed)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Len(t, g.calls, 3, nil)
+	assert.Equal(t, "index,id,input,formatted_address,latitude,longitude,place_id,error\n"+
+		"0,1,1 Main St,\"1 Main St, Halifax\",44.6,-63.5,id-1 Main St,\n"+
+		"1,2,nowhere,,,,,maps: ZERO_RESULTS\n"+
+		"2,3,\"44.64,-63.57\",Spring Garden Rd,44.64,-63.57,,\n"+
+		"3,4,,,,,,row has no address or latitude and longitude\n", readOutput(t, m, job.ID))
+	assert.Equal(t, []Job{job}, m.List())
+
+	_, err = m.Submit(strings.NewReader("name\nx\n"), FormatCSV)
+	assert.Error(t, err)
+	_, err = m.Submit(strings.NewReader(""), FormatJSONL)
+	assert.Error(t, err)
+	assert.Len(t, m.List(), 1)
+}
+
+// Testing an interrupted job resumes from its checkpoint, skipping rows it already has results for
+func Test_Resume(t *testing.T) {
+	dir := t.TempDir()
+	jobDir := filepath.Join(dir, "job1")
+	assert.NoError(t, os.MkdirAll(jobDir, 0o755))
+	input := `{"address": "a"}` + "\n" + `{"address": "b"}` + "\n" + `{"address": "nowhere"}` + "\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(jobDir, "input.jsonl"), []byte(input), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(jobDir, "job.json"), []byte(`{"id": "job1", "format": "jsonl", "status": "running", "total": 3}`), 0o644))
+	// Row 0 finished before the crash, row 2 was being written
+	checkpoint := `{"index": 0, "input": "a", "formattedAddress": "a, Halifax"}` + "\n" + `{"index": 2, "inp`
+	assert.NoError(t, os.WriteFile(filepath.Join(jobDir, "results.jsonl"), []byte(checkpoint), 0o644))
+
+	g := &fakeGeocoder{}
+	m, err := NewManager(dir, g, 2, nil)
+	assert.NoError(t, err)
+	defer m.Close()
+	job := waitDone(t, m, "job1")
+	assert.Equal(t, StatusDone, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 1, job.Failed)
+	assert.ElementsMatch(t, []string{"b", "nowhere"}, g.calls)
+	assert.Equal(t, `{"index":0,"input":"a","formattedAddress":"a, Halifax"}
+{"index":1,"input":"b","formattedAddress":"b, Halifax","location":{"lat":44.6,"lng":-63.5},"placeId":"id-b"}
+{"index":2,"input":"nowhere","error":"maps: ZERO_RESULTS"}
+`, readOutput(t, m, "job1"))
+
+	// Finished jobs are not run again
+	m.Close()
+	g = &fakeGeocoder{}
+	m, _ = NewManager(dir, g, 2, nil)
+	m.Close()
+	assert.Empty(t, g.calls)
+	job, _ = m.Get("job1")
+	assert.Equal(t, StatusDone, job.Status)
+}
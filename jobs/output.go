@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/geolocate/geo"
+)
+
+// Result is the outcome of one row
+type Result struct {
+	Index            int         `json:"index"`
+	ID               string      `json:"id,omitempty"`
+	Input            string      `json:"input"` // Address, or latitude,longitude
+	FormattedAddress string      `json:"formattedAddress,omitempty"`
+	Location         *geo.LatLng `json:"location,omitempty"`
+	PlaceID          string      `json:"placeId,omitempty"`
+	Error            string      `json:"error,omitempty"`
+}
+
+// Columns of CSV output
+var csvHeader = []string{"index", "id", "input", "formatted_address", "latitude", "longitude", "place_id", "error"}
+
+func writeResult(w io.Writer, r Result) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Write the checkpointed results to path in input order
+func writeOutput(checkpoint, path string, format Format) error {
+	results, err := readResults(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if format == FormatCSV {
+		err = writeCSV(w, results)
+	} else {
+		for _, r := range results {
+			if err = writeResult(w, r); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// The checkpointed results sorted by index, one per row
+func readResults(path string) ([]Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var results []Result
+	seen := map[int]bool{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Result
+		if json.Unmarshal(scanner.Bytes(), &r) != nil || seen[r.Index] {
+			continue
+		}
+		seen[r.Index] = true
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	return results, scanner.Err()
+}
+
+func writeCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		lat, lng := "", ""
+		if r.Location != nil {
+			lat, lng = strconv.FormatFloat(r.Location.Lat, 'f', -1, 64), strconv.FormatFloat(r.Location.Lng, 'f', -1, 64)
+		}
+		if err := cw.Write([]string{strconv.Itoa(r.Index), r.ID, r.Input, r.FormattedAddress, lat, lng, r.PlaceID, r.Error}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -1,31 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
+	_ "time/tzdata" // Place time zones for opening hours checks, even where the host has no zoneinfo
 
 	"github.com/geolocate/server"
-	"github.com/gorilla/mux"
 )
 
 func main() {
-	// Create a new router
-	r := mux.NewRouter()
-
-	// Define routes
-	r.HandleFunc("/getplace/{placeID}", server.GetPlacebyId).Methods("GET")
-	r.HandleFunc("/geocode", server.GetGeocode).Methods("GET")
-	r.HandleFunc("/geodecode", server.GetGeodecode).Methods("GET")
-	r.HandleFunc("/nearbysearch", server.GetPlacesNearby).Methods("POST")
-	r.HandleFunc("/textsearch", server.GetPlacesFromText).Methods("POST")
-
-	r.HandleFunc("/types", server.GetAllTypes).Methods("GET")
-	r.HandleFunc("/defaulttypes", server.GetDefaultTypes).Methods("GET")
+	s, err := server.New(server.ConfigFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.Start(context.Background())
 
 	// Start server
 	srv := &http.Server{
-		Handler:      r,
+		Handler:      s,
 		Addr:         "127.0.0.1:8000",
 		WriteTimeout: 10 * time.Second,
 		ReadTimeout:  10 * time.Second,
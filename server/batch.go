@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/geolocate/cache"
+	"github.com/geolocate/geo"
+)
+
+// Most items a batch request may hold, and how many of them are looked up at once
+var maxBatchItems = 50
+var batchConcurrency = 8
+
+// Define a struct to match the expected JSON body
+type PlacesBatchGet struct {
+	PlaceIDs []string `json:"placeIds"`
+}
+
+// Define a struct to match the expected JSON body
+type GeocodeBatch struct {
+	Addresses []string `json:"addresses"`
+	Lat       float64  `json:"latitude,omitempty"` // Reference location for short plus codes
+	Long      float64  `json:"longitude,omitempty"`
+}
+
+// BatchResult is the outcome for one item of a batch, in the position of the item in the request
+type BatchResult struct {
+	Input string       `json:"input"`
+	Data  any          `json:"data,omitempty"`
+	Error string       `json:"error,omitempty"`
+	Cache cache.Status `json:"cache,omitempty"` // HIT, STALE or MISS when the item went through the response cache
+}
+
+// Look up each distinct input once, at most batchConcurrency at a time. Results are in input order,
+// repeated inputs sharing the result of the first
+func fanOut(ctx context.Context, inputs []string, fetch func(ctx context.Context, input string) (any, cache.Status, error)) []BatchResult {
+	unique := map[string]*BatchResult{}
+	var order []string
+	for _, input := range inputs {
+		if _, ok := unique[input]; !ok {
+			unique[input] = &BatchResult{Input: input}
+			order = append(order, input)
+		}
+	}
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for _, input := range order {
+		result := unique[input]
+		if strings.TrimSpace(input) == "" {
+			result.Error = "empty input"
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			data, status, err := fetch(ctx, input)
+			result.Cache = status
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Data = data
+		}()
+	}
+	wg.Wait()
+	results := make([]BatchResult, 0, len(inputs))
+	for _, input := range inputs {
+		results = append(results, *unique[input])
+	}
+	return results
+}
+
+func validateBatch(n int, what string) error {
+	if n == 0 {
+		return fmt.Errorf("Please enter at least one %s", what)
+	}
+	if n > maxBatchItems {
+		return fmt.Errorf("At most %d %ss may be looked up at once", maxBatchItems, what)
+	}
+	return nil
+}
+
+// Look up the details of several places at once. Each place is served from the response cache when it can be
+func (s *Server) GetPlacesBatch(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	var params PlacesBatchGet
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	if err := validateBatch(len(params.PlaceIDs), "placeId"); err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	header := geo.PlacesHeader{FieldMasks: defaultFieldMask, FieldMaskPrefix: false}
+	results := fanOut(r.Context(), params.PlaceIDs, func(ctx context.Context, placeID string) (any, cache.Status, error) {
+		place, status, err := cache.Fetch(ctx, s.cache, cache.Key("place", placeID, header), func(ctx context.Context) (geo.Place, error) {
+			place, err := s.client.PlaceDetails(ctx, placeID, &header)
+			s.saveFetched(err, place)
+			return place, err
+		})
+		if err != nil {
+			return nil, status, err
+		}
+		return geo.WithPlusCode(place), status, nil
+	})
+	// Exports hold the places found. Per item errors are only in the JSON results
+	var places []geo.Place
+	for _, result := range results {
+		if place, ok := result.Data.(geo.Place); ok {
+			places = append(places, place)
+		}
+	}
+	exp.respond(w, results, placeItems(places...))
+}
+
+// Geocode several addresses at once. Plus codes are decoded locally, other addresses are served from the
+// response cache when they can be
+func (s *Server) GetGeocodeBatch(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	var params GeocodeBatch
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	if err := validateBatch(len(params.Addresses), "address"); err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	results := fanOut(r.Context(), params.Addresses, func(ctx context.Context, address string) (any, cache.Status, error) {
+		address = strings.TrimSpace(address)
+		if geo.IsValidPlusCode(address) {
+			code, err := recoverPlusCode(address, params.Lat, params.Long)
+			if err != nil {
+				return nil, "", err
+			}
+			geocode, err := geo.GeocodePlusCode(code)
+			return geocode, "", err
+		}
+		req := geo.GeocodingRequest{Address: address}
+		geocode, status, err := cache.Fetch(ctx, s.cache, cache.Key("geocode", req), func(ctx context.Context) (geo.GeocodingResponse, error) {
+			return s.client.Geocode(ctx, &req)
+		})
+		return geocode, status, err
+	})
+	// Exports hold the results of the addresses geocoded. Per item errors are only in the JSON results
+	var geocoded []geo.GeocodingResult
+	for _, result := range results {
+		if geocode, ok := result.Data.(geo.GeocodingResponse); ok {
+			geocoded = append(geocoded, geocode.Results...)
+		}
+	}
+	exp.respond(w, results, geocodeItems(geocoded...))
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geolocate/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing inputs are looked up once each, at most batchConcurrency at a time, with results in input order
+func Test_FanOut(t *testing.T) {
+	var mu sync.Mutex
+	calls, running, peak := map[string]int{}, 0, 0
+	results := fanOut(context.Background(), []string{"a", "b", "a", "", "c", "d", "e", "f", "g", "h", "i", "j"}, func(ctx context.Context, input string) (any, cache.Status, error) {
+		mu.Lock()
+		calls[input]++
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if input == "b" {
+			return nil, cache.StatusMiss, errors.New("not found")
+		}
+		return strings.ToUpper(input), cache.StatusHit, nil
+	})
+	assert.Len(t, results, 12)
+	assert.Equal(t, BatchResult{Input: "a", Data: "A", Cache: cache.StatusHit}, results[0])
+	assert.Equal(t, BatchResult{Input: "b", Error: "not found", Cache: cache.StatusMiss}, results[1])
+	assert.Equal(t, results[0], results[2])
+	assert.Equal(t, BatchResult{Input: "", Error: "empty input"}, results[3])
+	assert.Equal(t, "J", results[11].Data)
+	assert.Equal(t, 1, calls["a"])
+	assert.Len(t, calls, 10)
+	assert.LessOrEqual(t, peak, batchConcurrency)
+}
+
+// Testing batches must hold between one and maxBatchItems items
+func Test_BatchValidation(t *testing.T) {
+	s := newTestServer(t, Config{}, nil)
+	for _, tc := range []struct {
+		handler http.HandlerFunc
+		body    string
+	}{
+		{s.GetPlacesBatch, `{"placeIds": []}`},
+		{s.GetPlacesBatch, `{"placeIds": [` + strings.Repeat(`"a",`, maxBatchItems) + `"a"]}`},
+		{s.GetGeocodeBatch, `{"addresses": "Halifax"}`},
+		{s.GetGeocodeBatch, `{}`},
+	} {
+		w := httptest.NewRecorder()
+		tc.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.body)
+	}
+}
+
+// Testing batchGet exports the places found in the negotiated format and leaves failed lookups out
+func Test_PlacesBatch_Export(t *testing.T) {
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": 404, "message": "Not found", "status": "NOT_FOUND"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id": %q, "displayName": {"text": "Cafe"}, "location": {"latitude": 44.6, "longitude": -63.5}}`, path.Base(r.URL.Path))
+	})
+	batch := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"placeIds": ["a", "missing", "b"]}`)))
+		return w
+	}
+
+	w := batch("/places:batchGet?format=csv&columns=id,name")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id,name\na,Cafe\nb,Cafe\n", w.Body.String())
+
+	w = batch("/places:batchGet")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []BatchResult `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Data, 3)
+	assert.NotEmpty(t, resp.Data[1].Error)
+
+	assert.Equal(t, http.StatusBadRequest, batch("/places:batchGet?format=xml").Code)
+}
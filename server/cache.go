@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/geolocate/cache"
+)
+
+var cacheCapacity = 1000
+
+// Expired responses are still served for this long while they are refreshed
+var cacheStaleFor = time.Hour
+
+// Upstream responses are cached within Google's terms. Config.CacheDir keeps them on disk across restarts,
+// otherwise the most recent cacheCapacity responses are kept in memory. Config.CacheTTL sets how long
+// content is fresh, up to 30 days
+func (s *Server) newResponseCache() *cache.Cache {
+	var backend cache.Backend = cache.NewMemory(cacheCapacity)
+	if s.config.CacheDir != "" {
+		disk, err := cache.NewDisk(s.config.CacheDir)
+		if err != nil {
+			s.logger.Printf("cache: using memory, %s", err)
+		} else {
+			backend = disk
+		}
+	}
+	opts := []cache.Option{cache.WithStaleWhileRevalidate(cacheStaleFor)}
+	if s.config.CacheTTL > 0 {
+		opts = append(opts, cache.WithTTL(s.config.CacheTTL))
+	}
+	return cache.New(backend, opts...)
+}
+
+// Serve fetch through the response cache, reporting HIT, STALE or MISS in the X-Cache header
+func cached[T any](w http.ResponseWriter, ctx context.Context, c *cache.Cache, key string, fetch func(context.Context) (T, error)) (T, error) {
+	v, status, err := cache.Fetch(ctx, c, key, fetch)
+	w.Header().Set("X-Cache", string(status))
+	return v, err
+}
+
+// Delete cached responses. prefix limits the purge to one kind of request. Eg: prefix=textsearch:
+// Requires the X-Admin-Token header to match Config.AdminToken
+func (s *Server) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		responseJson(w, http.StatusForbidden, Response{Data: nil, Error: "Admin token missing or invalid"})
+		return
+	}
+	purged, err := s.cache.Purge(r.URL.Query().Get("prefix"))
+	if err != nil {
+		responseJson(w, http.StatusInternalServerError, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	responseJson(w, http.StatusOK, Response{Data: map[string]int{"purged": purged}, Error: ""})
+}
+
+// Admin routes require the X-Admin-Token header to match Config.AdminToken. Compared in constant time
+// so response times do not reveal how much of a guess was right
+func (s *Server) isAdmin(r *http.Request) bool {
+	return s.config.AdminToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(s.config.AdminToken)) == 1
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geolocate/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing cached responses report their status and the admin route purges them
+func Test_PurgeCache(t *testing.T) {
+	s := newTestServer(t, Config{AdminToken: "secret"}, nil)
+	fetch := func(context.Context) (string, error) { return "Halifax", nil }
+	w := httptest.NewRecorder()
+	_, err := cached(w, context.Background(), s.cache, cache.Key("geocode", "Halifax"), fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	w = httptest.NewRecorder()
+	cached(w, context.Background(), s.cache, cache.Key("geocode", "Halifax"), fetch)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+
+	w = httptest.NewRecorder()
+	s.PurgeCache(w, httptest.NewRequest(http.MethodDelete, "/admin/cache", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	r := httptest.NewRequest(http.MethodDelete, "/admin/cache?prefix=geocode:", nil)
+	r.Header.Set("X-Admin-Token", "secret")
+	w = httptest.NewRecorder()
+	s.PurgeCache(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {"purged": 1}}`, w.Body.String())
+}
+
+// Testing only the exact admin token is accepted, and none when admin routes are off
+func Test_IsAdmin(t *testing.T) {
+	s := newTestServer(t, Config{AdminToken: "secret"}, nil)
+	for token, want := range map[string]bool{"secret": true, "": false, "secre": false, "secret2": false, "SECRET": false} {
+		r := httptest.NewRequest(http.MethodGet, "/admin/refresh", nil)
+		r.Header.Set("X-Admin-Token", token)
+		assert.Equal(t, want, s.isAdmin(r), token)
+	}
+	s = newTestServer(t, Config{}, nil)
+	assert.False(t, s.isAdmin(httptest.NewRequest(http.MethodGet, "/admin/refresh", nil)))
+}
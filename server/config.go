@@ -0,0 +1,45 @@
+package server
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// Config holds the server's settings. Zero values take the defaults
+type Config struct {
+	APIKey     string // Google Maps Platform API key. Routes calling Google respond 503 without one
+	AdminToken string // Admin routes require the X-Admin-Token header to match. Admin routes are off when empty
+
+	CacheDir string        // Keep cached responses on disk across restarts, otherwise the most recent cacheCapacity are kept in memory
+	CacheTTL time.Duration // How long cached content is fresh, up to 30 days
+
+	StorePath string // Keep every place returned in this file, otherwise they are held in memory
+	WatchDir  string // Keep watchlists and undelivered events on disk
+	JobsDir   string // Keep batch jobs here so they resume after a restart. Defaults to a directory under os.TempDir
+
+	DisableSearchFallback bool // Return Google's quota and unavailable errors instead of searching stored places
+}
+
+// ConfigFromEnv reads the config from API_KEY, ADMIN_TOKEN, CACHE_DIR, CACHE_TTL, STORE_PATH, WATCH_DIR,
+// JOBS_DIR and SEARCH_FALLBACK
+func ConfigFromEnv() Config {
+	config := Config{
+		APIKey:                os.Getenv("API_KEY"),
+		AdminToken:            os.Getenv("ADMIN_TOKEN"),
+		CacheDir:              os.Getenv("CACHE_DIR"),
+		StorePath:             os.Getenv("STORE_PATH"),
+		WatchDir:              os.Getenv("WATCH_DIR"),
+		JobsDir:               os.Getenv("JOBS_DIR"),
+		DisableSearchFallback: os.Getenv("SEARCH_FALLBACK") == "off",
+	}
+	if v := os.Getenv("CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			log.Printf("config: ignoring CACHE_TTL %q", v)
+		} else {
+			config.CacheTTL = ttl
+		}
+	}
+	return config
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/geolocate/export"
+	"github.com/geolocate/geo"
+	"github.com/geolocate/store"
+)
+
+// Properties the server adds to places, which may also be picked as CSV columns
+var exportProperties = []string{"distanceMeters", "bearing", "openDuring", "city", "score"}
+
+// How a search, details or geocode route responds: the JSON Response, or an export picked by the format
+// query param or Accept header. CSV columns are picked with columns. Eg: ?format=csv&columns=name,latitude,longitude
+type exportRequest struct {
+	format export.Format
+	opts   export.Options
+}
+
+// Negotiate the response format, responding 400 to an unknown format or column
+func exportFormat(w http.ResponseWriter, r *http.Request) (exportRequest, bool) {
+	query := r.URL.Query()
+	format, err := export.Negotiate(r.Header.Get("Accept"), query.Get("format"))
+	if err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return exportRequest{}, false
+	}
+	exp := exportRequest{format: format}
+	if columns := query.Get("columns"); columns != "" {
+		exp.opts.Columns = strings.Split(columns, ",")
+		if err := export.ValidateColumns(exp.opts.Columns, exportProperties...); err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return exportRequest{}, false
+		}
+	}
+	return exp, true
+}
+
+// Respond with data as JSON, or stream items in the negotiated format
+func (exp exportRequest) respond(w http.ResponseWriter, data any, items []export.Item) {
+	if exp.format == export.FormatJSON {
+		responseJson(w, http.StatusOK, Response{Data: data, Error: ""}) // Success
+		return
+	}
+	w.Header().Set("Content-Type", export.ContentType(exp.format))
+	ew, err := export.NewWriter(w, exp.format, exp.opts)
+	if err != nil {
+		responseJson(w, http.StatusInternalServerError, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	// The status is already sent, so a failed write means the client went away
+	for _, item := range items {
+		if ew.Write(item) != nil {
+			return
+		}
+	}
+	ew.Close()
+}
+
+func searchItems(result SearchResult) []export.Item {
+	items := make([]export.Item, 0, len(result.Places))
+	for _, p := range result.Places {
+		item := export.Item{Place: p.Place, Properties: []export.Property{{Name: "distanceMeters", Value: p.DistanceMeters}, {Name: "bearing", Value: p.Bearing}}}
+		if p.OpenDuring != "" {
+			item.Properties = append(item.Properties, export.Property{Name: "openDuring", Value: p.OpenDuring})
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func placeItems(places ...geo.Place) []export.Item {
+	items := make([]export.Item, 0, len(places))
+	for _, p := range places {
+		items = append(items, export.Item{Place: p})
+	}
+	return items
+}
+
+// Geocoding results exported as places at their geometry's location
+func geocodeItems(results ...geo.GeocodingResult) []export.Item {
+	items := make([]export.Item, 0, len(results))
+	for _, r := range results {
+		place := geo.Place{Id: r.PlaceID, Types: r.Types, FormattedAddress: r.FormattedAddress, Location: r.Geometry.Location.Location()}
+		if r.PlusCode.GlobalCode != "" {
+			place.PlusCode = &geo.PlacePlusCode{GlobalCode: r.PlusCode.GlobalCode, CompoundCode: r.PlusCode.CompoundCode}
+		}
+		items = append(items, export.Item{Place: place})
+	}
+	return items
+}
+
+func storedItems(results ...store.Result) []export.Item {
+	items := make([]export.Item, 0, len(results))
+	for _, r := range results {
+		item := export.Item{Place: r.Place, Properties: []export.Property{{Name: "city", Value: r.City}, {Name: "distanceMeters", Value: r.DistanceMeters}}}
+		if r.City == "" {
+			item.Properties[0].Value = nil
+		}
+		if r.Score > 0 {
+			item.Properties = append(item.Properties, export.Property{Name: "score", Value: r.Score})
+		}
+		items = append(items, item)
+	}
+	return items
+}
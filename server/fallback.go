@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/geolocate/client"
+	"github.com/geolocate/geo"
+	"github.com/geolocate/store"
+)
+
+// Source of search results served from the place store instead of Google
+const sourceLocal = "local"
+
+// Whether err means Google will not serve the request for now: 429 once the quota is exhausted,
+// 503 while the API is unavailable. There is no circuit breaker, so Google's 503 stands in for an open
+// circuit and every search still tries Google first. Searches then fall back to places stored from
+// earlier responses, unless Config.DisableSearchFallback is set
+func (s *Server) useLocalFallback(err error) bool {
+	var httpErr client.HttpError
+	return !s.config.DisableSearchFallback && errors.As(err, &httpErr) &&
+		(httpErr.Status == http.StatusTooManyRequests || httpErr.Status == http.StatusServiceUnavailable)
+}
+
+// Stored places of the requested types within the search circle, nearest first
+func (s *Server) localNearby(params PlacesNearby, types []geo.PlaceType, limit int) geo.PlacesSearchResponse {
+	q := store.Query{Near: &geo.Location{Latitude: params.Lat, Longitude: params.Long}, Radius: float64(params.Radius), Limit: limit}
+	for _, t := range types {
+		q.Types = append(q.Types, string(t))
+	}
+	return localResponse(s.store.Find(q))
+}
+
+// Stored places matching the search text, biased towards the user's location like Google's text search.
+// Searches restricted to the user's locality keep places inside the viewport cached for their cell, and
+// find nothing when it is not cached. Searches naming a locality, or whose locality was resolved, drop
+// places stored with another city. With filterOpen closed places are dropped before the page is cut,
+// as Google's pages are refilled
+func (s *Server) localText(params PlacesFromText, filterOpen bool) geo.PlacesSearchResponse {
+	origin := searchOrigin(params.Lat, params.Long)
+	q := store.SearchQuery{Text: params.Text, Near: origin, Radius: float64(params.Radius)}
+	results := s.store.Search(q)
+	locality := params.Locality
+	if origin != nil && (params.RestrictToLocality || locality == "") {
+		entry, ok := s.localities.get(geo.EncodeGeohash(params.Lat, params.Long, localityCellPrecision), time.Now())
+		switch {
+		case params.RestrictToLocality && !ok:
+			results = nil
+		case params.RestrictToLocality:
+			results = slices.DeleteFunc(results, func(r store.Result) bool { return !entry.Viewport.Contains(r.Location) })
+		case ok:
+			locality = entry.Locality
+		}
+	}
+	if city, _, _ := strings.Cut(locality, ","); !params.RestrictToLocality && city != "" {
+		// "Halifax, NS, Canada" names the city Halifax. Places stored without a city are kept
+		results = slices.DeleteFunc(results, func(r store.Result) bool {
+			return r.City != "" && !strings.EqualFold(r.City, strings.TrimSpace(city))
+		})
+	}
+	resp := localResponse(results)
+	if filterOpen {
+		resp.Places = geo.FilterOpenDuring(resp.Places, params.OpenDuring.From, params.OpenDuring.To)
+	}
+	if len(resp.Places) > int(resultCount) {
+		resp.Places = resp.Places[:resultCount]
+	}
+	return resp
+}
+
+func localResponse(results []store.Result) geo.PlacesSearchResponse {
+	resp := geo.PlacesSearchResponse{Places: make([]geo.Place, 0, len(results))}
+	for _, r := range results {
+		resp.Places = append(resp.Places, r.Place)
+	}
+	return resp
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/geolocate/client"
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing only quota and unavailable errors fall back, unless the fallback is off
+func Test_UseLocalFallback(t *testing.T) {
+	s := newTestServer(t, Config{}, nil)
+	assert.True(t, s.useLocalFallback(client.HttpError{Status: 429}))
+	assert.True(t, s.useLocalFallback(client.HttpError{Status: 503}))
+	assert.False(t, s.useLocalFallback(client.HttpError{Status: 400}))
+	assert.False(t, s.useLocalFallback(errors.New("timeout")))
+	assert.False(t, s.useLocalFallback(nil))
+	s = newTestServer(t, Config{DisableSearchFallback: true}, nil)
+	assert.False(t, s.useLocalFallback(client.HttpError{Status: 429}))
+}
+
+func cityComponents(city string) []geo.PlaceAddressComponent {
+	return []geo.PlaceAddressComponent{{LongText: city, ShortText: city, Types: []string{"locality", "political"}}}
+}
+
+// Testing stored places answer nearby and text searches
+func Test_LocalSearch(t *testing.T) {
+	s := newTestServer(t, Config{}, nil)
+	allDay := geo.OpeningHours{Periods: []geo.Period{{Open: geo.Point{Day: 0}}}}
+	s.savePlaces(
+		geo.Place{Id: "pizza", DisplayName: geo.LocalizedText{Text: "Pizza Corner"}, PrimaryType: "pizza_restaurant", Location: geo.Location{Latitude: 44.645, Longitude: -63.573}, RegularOpeningHours: allDay, AddressComponents: cityComponents("Halifax")},
+		geo.Place{Id: "cafe", DisplayName: geo.LocalizedText{Text: "Harbour Cafe"}, PrimaryType: "cafe", Location: geo.Location{Latitude: 44.648, Longitude: -63.570}, AddressComponents: cityComponents("Halifax")},
+		geo.Place{Id: "far", DisplayName: geo.LocalizedText{Text: "Boston Pizza"}, PrimaryType: "pizza_restaurant", Location: geo.Location{Latitude: 42.36, Longitude: -71.05}, AddressComponents: cityComponents("Boston")},
+	)
+
+	nearby := s.localNearby(PlacesNearby{Lat: 44.6488, Long: -63.5752, Radius: 1000}, []geo.PlaceType{geo.PizzaRestaurant, geo.Cafe}, 20)
+	assert.Equal(t, []string{"cafe", "pizza"}, nearby.PlaceIDs())
+
+	// The user's locality is unknown, so far away places are only ranked lower
+	text := s.localText(PlacesFromText{Text: "pizza", Lat: 44.6488, Long: -63.5752}, false)
+	assert.Equal(t, []string{"pizza", "far"}, text.PlaceIDs())
+	window := &OpenWindow{From: time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)}
+	text = s.localText(PlacesFromText{Text: "pizza cafe", Lat: 44.6488, Long: -63.5752, OpenDuring: window}, true)
+	assert.Equal(t, []string{"pizza"}, text.PlaceIDs())
+}
+
+// Testing local text searches keep to the named, resolved or restricted locality
+func Test_LocalSearch_Locality(t *testing.T) {
+	s := newTestServer(t, Config{}, nil)
+	s.savePlaces(
+		geo.Place{Id: "downtown", DisplayName: geo.LocalizedText{Text: "Pizza Corner"}, Location: geo.Location{Latitude: 44.645, Longitude: -63.573}, AddressComponents: cityComponents("Halifax")},
+		geo.Place{Id: "dartmouth", DisplayName: geo.LocalizedText{Text: "Dartmouth Pizza"}, Location: geo.Location{Latitude: 44.67, Longitude: -63.57}, AddressComponents: cityComponents("Dartmouth")},
+		geo.Place{Id: "far", DisplayName: geo.LocalizedText{Text: "Boston Pizza"}, Location: geo.Location{Latitude: 42.36, Longitude: -71.05}, AddressComponents: cityComponents("Boston")},
+		geo.Place{Id: "unknown", DisplayName: geo.LocalizedText{Text: "Pizza Van"}, Location: geo.Location{Latitude: 44.70, Longitude: -63.60}},
+	)
+
+	text := s.localText(PlacesFromText{Text: "pizza", Locality: "Halifax, NS, Canada"}, false)
+	assert.ElementsMatch(t, []string{"downtown", "unknown"}, text.PlaceIDs())
+
+	lat, long := 44.6488, -63.5752
+	viewport := geo.Rectangle{Low: geo.Location{Latitude: 44.6, Longitude: -63.65}, High: geo.Location{Latitude: 44.66, Longitude: -63.55}}
+	s.localities.put(geo.EncodeGeohash(lat, long, localityCellPrecision), cellLocality{Locality: "Halifax, NS, Canada", Viewport: &viewport}, time.Now())
+	text = s.localText(PlacesFromText{Text: "pizza", Lat: lat, Long: long}, false)
+	assert.Equal(t, []string{"downtown", "unknown"}, text.PlaceIDs())
+
+	text = s.localText(PlacesFromText{Text: "pizza", Lat: lat, Long: long, RestrictToLocality: true}, false)
+	assert.Equal(t, []string{"downtown"}, text.PlaceIDs())
+
+	// Without the cell's viewport nothing can be shown to be inside the locality
+	text = s.localText(PlacesFromText{Text: "pizza", Lat: 42.36, Long: -71.05, RestrictToLocality: true}, false)
+	assert.Empty(t, text.PlaceIDs())
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/geolocate/jobs"
+	"github.com/gorilla/mux"
+)
+
+// Largest job input accepted
+var maxJobBytes int64 = 32 << 20
+
+// Batch geocoding jobs run in the background, kept under Config.JobsDir so they resume after a restart.
+// The server's client, and so its rate limiter, is shared by all of a job's workers
+func (s *Server) newJobs() *jobs.Manager {
+	dir := s.config.JobsDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "geolocate-jobs")
+	}
+	m, err := jobs.NewManager(dir, s.client, 0, s.logger)
+	if err != nil {
+		s.logger.Printf("jobs: not started, %s", err)
+		return nil
+	}
+	return m
+}
+
+// Job routes are admin only, like watchlists, as a job may spend up to jobs.MaxRows geocodes of the API quota
+func (s *Server) jobsReady(w http.ResponseWriter, r *http.Request) bool {
+	if !s.isAdmin(r) {
+		responseJson(w, http.StatusForbidden, Response{Data: nil, Error: "Admin token missing or invalid"})
+		return false
+	}
+	if s.jobs == nil {
+		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: "Batch jobs are not running, API_KEY is not set"})
+		return false
+	}
+	return true
+}
+
+// Submit a batch geocoding job. The body is a JSONL or CSV file of addresses or latitude/longitude pairs.
+// Its format is taken from the format query param, then the Content-Type, then the content itself
+func (s *Server) SubmitJob(w http.ResponseWriter, r *http.Request) {
+	if !s.jobsReady(w, r) {
+		return
+	}
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxJobBytes))
+	peek, _ := body.Peek(512)
+	format := jobs.Format(r.URL.Query().Get("format"))
+	if format != jobs.FormatCSV && format != jobs.FormatJSONL {
+		var err error
+		if format, err = jobs.DetectFormat(r.Header.Get("Content-Type"), peek); err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+	}
+	job, err := s.jobs.Submit(body, format)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		responseJson(w, http.StatusRequestEntityTooLarge, Response{Data: nil, Error: err.Error()})
+	case err != nil:
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+	default:
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		responseJson(w, http.StatusAccepted, Response{Data: job, Error: ""})
+	}
+}
+
+// List batch jobs, newest first
+func (s *Server) GetJobs(w http.ResponseWriter, r *http.Request) {
+	if !s.jobsReady(w, r) {
+		return
+	}
+	responseJson(w, http.StatusOK, Response{Data: s.jobs.List(), Error: ""})
+}
+
+// Look up a batch job's status and progress by jobID
+func (s *Server) GetJob(w http.ResponseWriter, r *http.Request) {
+	if !s.jobsReady(w, r) {
+		return
+	}
+	job, ok := s.jobs.Get(mux.Vars(r)["jobID"])
+	if !ok {
+		responseJson(w, http.StatusNotFound, Response{Data: nil, Error: "Job not found"})
+		return
+	}
+	responseJson(w, http.StatusOK, Response{Data: job, Error: ""})
+}
+
+// Download a finished job's results, one per input row in input order and in the input's format
+func (s *Server) GetJobResults(w http.ResponseWriter, r *http.Request) {
+	if !s.jobsReady(w, r) {
+		return
+	}
+	id := mux.Vars(r)["jobID"]
+	out, format, err := s.jobs.Output(id)
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		responseJson(w, http.StatusNotFound, Response{Data: nil, Error: "Job not found"})
+		return
+	case errors.Is(err, jobs.ErrNotDone):
+		responseJson(w, http.StatusConflict, Response{Data: nil, Error: "Job is not done yet"})
+		return
+	case err != nil:
+		responseJson(w, http.StatusInternalServerError, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	defer out.Close()
+	contentType := "application/x-ndjson"
+	if format == jobs.FormatCSV {
+		contentType = "text/csv"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+id+`-results.`+string(format)+`"`)
+	io.Copy(w, out)
+}
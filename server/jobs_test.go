@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geolocate/jobs"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing a job is submitted, followed and downloaded through the routes
+func Test_Jobs(t *testing.T) {
+	s := newTestServer(t, Config{AdminToken: "secret"}, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status": "OK", "results": [{"formatted_address": "Boston, MA, USA", "place_id": "boston",
+			"geometry": {"location": {"lat": 42.36, "lng": -71.06}}}]}`)
+	})
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.Header.Set("X-Admin-Token", "secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"address": "Boston"}`)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/jobs", "name\nBoston\n").Code)
+
+	w = do(http.MethodPost, "/jobs", `{"id": "1", "address": "Boston"}`+"\n")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var submitted struct {
+		Data jobs.Job `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&submitted))
+	assert.Equal(t, jobs.FormatJSONL, submitted.Data.Format)
+	assert.Equal(t, "/jobs/"+submitted.Data.ID, w.Header().Get("Location"))
+
+	assert.Eventually(t, func() bool {
+		w = do(http.MethodGet, "/jobs/"+submitted.Data.ID, "")
+		return strings.Contains(w.Body.String(), `"status":"done"`)
+	}, 5*time.Second, 5*time.Millisecond)
+
+	w = do(http.MethodGet, "/jobs/"+submitted.Data.ID+"/results", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"index":0,"id":"1","input":"Boston","formattedAddress":"Boston, MA, USA","location":{"lat":42.36,"lng":-71.06},"placeId":"boston"}`+"\n", w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/jobs/missing/results", "").Code)
+}
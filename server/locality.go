@@ -0,0 +1,166 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geolocate/cache"
+	"github.com/geolocate/geo"
+)
+
+// Localities are cached per geohash cell of this precision, about 5x5 km. Users in the same
+// cell nearly always share a city, so one reverse geocode serves all of them
+const localityCellPrecision = 5
+
+// How long a resolved locality is reused before it is looked up again
+var localityTTL = 24 * time.Hour
+
+// Locality resolved for a cell, with the viewport of the city used to restrict searches to it
+type cellLocality struct {
+	Locality string
+	Viewport *geo.Rectangle
+	expires  time.Time
+}
+
+// Viewport of the locality as Geocoding API bounds
+func (l cellLocality) bounds() geo.LatLngBounds {
+	return geo.LatLngBounds{SouthWest: l.Viewport.Low.LatLng(), NorthEast: l.Viewport.High.LatLng()}
+}
+
+// Most cells whose locality is kept. The least recently used cell is dropped to make room
+var localityCapacity = 10000
+
+// localityCache maps coarse cells to the locality that contains them
+type localityCache struct {
+	mu      sync.Mutex
+	order   *list.List // front is the most recently used
+	entries map[string]*list.Element
+}
+
+// A cell's locality in the cache
+type localityEntry struct {
+	cell string
+	cellLocality
+}
+
+func newLocalityCache() *localityCache {
+	return &localityCache{order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *localityCache) get(cell string, now time.Time) (cellLocality, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[cell]
+	if !ok {
+		return cellLocality{}, false
+	}
+	entry := el.Value.(localityEntry)
+	if now.After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, cell)
+		return cellLocality{}, false
+	}
+	c.order.MoveToFront(el)
+	return entry.cellLocality, true
+}
+
+func (c *localityCache) put(cell string, entry cellLocality, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.expires = now.Add(localityTTL)
+	if el, ok := c.entries[cell]; ok {
+		el.Value = localityEntry{cell, entry}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[cell] = c.order.PushFront(localityEntry{cell, entry})
+	if c.order.Len() > localityCapacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(localityEntry).cell)
+	}
+}
+
+// Result types asked of the reverse geocoder, from the city down. The first one found names the locality
+var localityResultTypes = []string{"locality", "postal_town", "sublocality", "administrative_area_level_2"}
+
+// resolveLocality reverse geocodes lat,long to the city containing it. Eg: "Boston, MA, United States"
+func (s *Server) resolveLocality(ctx context.Context, lat, long float64) (cellLocality, error) {
+	cell := geo.EncodeGeohash(lat, long, localityCellPrecision)
+	now := time.Now()
+	if entry, ok := s.localities.get(cell, now); ok {
+		return entry, nil
+	}
+	// Geocode the cell center so every user in the cell gets the same answer
+	center, err := geo.GeohashCenter(cell)
+	if err != nil {
+		return cellLocality{}, err
+	}
+	req := geo.GeocodingRequest{LatLng: &geo.LatLng{Lat: center.Latitude, Lng: center.Longitude}, ResultType: localityResultTypes}
+	resp, err := s.client.Geodecode(ctx, &req)
+	if err != nil {
+		return cellLocality{}, err
+	}
+	entry, ok := localityFromResults(resp.Results)
+	if !ok {
+		return cellLocality{}, errors.New("no locality found for this location")
+	}
+	s.localities.put(cell, entry, now)
+	return entry, nil
+}
+
+// The most specific result that names a city, preferring results in localityResultTypes order
+func localityFromResults(results []geo.GeocodingResult) (cellLocality, bool) {
+	for _, resultType := range localityResultTypes {
+		for _, result := range results {
+			if !slices.Contains(result.Types, resultType) {
+				continue
+			}
+			locality := result.Address().SearchLocality()
+			if locality == "" {
+				continue
+			}
+			viewport := result.Geometry.Viewport.Rectangle()
+			return cellLocality{Locality: locality, Viewport: &viewport}, true
+		}
+	}
+	return cellLocality{}, false
+}
+
+// Text search query limited to a locality by name. Eg: "Skating Rink in Boston, MA, United States"
+func localityQuery(text, locality string) string {
+	text, locality = strings.TrimSpace(text), strings.TrimSpace(locality)
+	if locality == "" {
+		return text
+	}
+	return text + " " + searchString + " " + locality
+}
+
+// Viewport of a region to search within. A named region is geocoded, otherwise the viewport of the
+// city containing lat,long is used
+func (s *Server) regionViewport(ctx context.Context, region string, lat, long float64) (geo.LatLngBounds, error) {
+	if region == "" {
+		locality, err := s.resolveLocality(ctx, lat, long)
+		if err != nil {
+			return geo.LatLngBounds{}, err
+		}
+		return locality.bounds(), nil
+	}
+	req := geo.GeocodingRequest{Address: region}
+	resp, _, err := cache.Fetch(ctx, s.cache, cache.Key("geocode", req), func(ctx context.Context) (geo.GeocodingResponse, error) {
+		return s.client.Geocode(ctx, &req)
+	})
+	if err != nil {
+		return geo.LatLngBounds{}, err
+	}
+	if len(resp.Results) == 0 {
+		return geo.LatLngBounds{}, fmt.Errorf("region %q not found", region)
+	}
+	return resp.Results[0].Geometry.Viewport, nil
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+const bostonGeocode = `{"status": "OK", "results": [
+	{"types": ["neighborhood", "political"], "address_components": [{"long_name": "Downtown", "short_name": "Downtown", "types": ["neighborhood"]}]},
+	{"types": ["locality", "political"],
+	 "address_components": [
+		{"long_name": "Boston", "short_name": "Boston", "types": ["locality", "political"]},
+		{"long_name": "Massachusetts", "short_name": "MA", "types": ["administrative_area_level_1", "political"]},
+		{"long_name": "United States", "short_name": "US", "types": ["country", "political"]}],
+	 "geometry": {"viewport": {"northeast": {"lat": 42.4, "lng": -70.9}, "southwest": {"lat": 42.2, "lng": -71.2}}}}
+]}`
+
+// Testing localities are reverse geocoded once per cell and cached
+func Test_ResolveLocality(t *testing.T) {
+	calls := 0
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/maps/api/geocode/json", r.URL.Path)
+		assert.Equal(t, "locality|postal_town|sublocality|administrative_area_level_2", r.URL.Query().Get("result_type"))
+		fmt.Fprint(w, bostonGeocode)
+	})
+
+	locality, err := s.resolveLocality(context.Background(), 42.3601, -71.0589)
+	assert.NoError(t, err)
+	assert.Equal(t, "Boston, MA, United States", locality.Locality)
+	assert.Equal(t, geo.Rectangle{Low: geo.Location{Latitude: 42.2, Longitude: -71.2}, High: geo.Location{Latitude: 42.4, Longitude: -70.9}}, *locality.Viewport)
+
+	_, err = s.resolveLocality(context.Background(), 42.3602, -71.0588) // same cell
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	_, err = s.resolveLocality(context.Background(), 42.5, -71.0589)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+// Testing the locality cache drops the least recently used cell when full and expired cells on lookup
+func Test_LocalityCache(t *testing.T) {
+	defer func(capacity int) { localityCapacity = capacity }(localityCapacity)
+	localityCapacity = 2
+	c := newLocalityCache()
+	now := time.Now()
+	c.put("a", cellLocality{Locality: "A"}, now)
+	c.put("b", cellLocality{Locality: "B"}, now)
+	_, ok := c.get("a", now)
+	assert.True(t, ok)
+	c.put("c", cellLocality{Locality: "C"}, now)
+	_, ok = c.get("b", now)
+	assert.False(t, ok)
+	entry, ok := c.get("a", now)
+	assert.True(t, ok)
+	assert.Equal(t, "A", entry.Locality)
+	assert.Len(t, c.entries, 2)
+
+	_, ok = c.get("c", now.Add(localityTTL+time.Second))
+	assert.False(t, ok)
+	assert.Len(t, c.entries, 1)
+	assert.Equal(t, 1, c.order.Len())
+}
+
+// Testing the text query is built with spaces around "in"
+func Test_LocalityQuery(t *testing.T) {
+	assert.Equal(t, "Skating Rink in Boston, MA, United States", localityQuery(" Skating Rink ", "Boston, MA, United States"))
+	assert.Equal(t, "Skating Rink", localityQuery("Skating Rink", ""))
+}
+
+// Testing a text search restricted to the locality searches its viewport and drops places outside it
+func Test_RestrictToLocality(t *testing.T) {
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/maps/api/geocode/json" {
+			fmt.Fprint(w, bostonGeocode)
+			return
+		}
+		var req geo.TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "pizza", req.TextQuery)
+		assert.Nil(t, req.LocationBias)
+		assert.Equal(t, &geo.RectangularRestriction{Rectangle: geo.Rectangle{Low: geo.Location{Latitude: 42.2, Longitude: -71.2}, High: geo.Location{Latitude: 42.4, Longitude: -70.9}}}, req.LocationRestriction)
+		fmt.Fprint(w, `{"places": [{"id": "boston", "location": {"latitude": 42.36, "longitude": -71.06}},
+			{"id": "cambridge", "location": {"latitude": 42.41, "longitude": -71.1}}]}`)
+	})
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/textsearch", strings.NewReader(`{"text": "pizza", "latitude": 42.3601, "longitude": -71.0589, "restrictToLocality": true}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data SearchResult `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, []string{"boston"}, resultIDs(resp.Data.Places))
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/store"
+	"github.com/gorilla/mux"
+)
+
+// Every place returned by a search or details lookup is kept in the store to be browsed without calling Google.
+// Config.StorePath keeps them on disk, otherwise they are held in memory until the server stops
+func (s *Server) openPlaceStore() *store.Store {
+	st, err := store.Open(s.config.StorePath)
+	if err != nil {
+		s.logger.Printf("store: keeping places in memory, %s", err)
+		st, _ = store.Open("")
+	}
+	return st
+}
+
+// Upsert places into the store. A failed write must not fail the request that found them
+func (s *Server) savePlaces(places ...geo.Place) {
+	if err := s.store.Upsert(places...); err != nil {
+		s.logger.Printf("store: %s", err)
+	}
+}
+
+// Store places just fetched from Google, from within a cache fetch. Places served from the response cache
+// are not stored again, as that would date content fetched up to CacheTTL ago as new
+func (s *Server) saveFetched(err error, places ...geo.Place) {
+	if err == nil {
+		s.savePlaces(places...)
+	}
+}
+
+// Browse stored places. Query params: city, types (comma separated), latitude, longitude and radius in meters,
+// openAt (RFC 3339) and limit
+func (s *Server) GetStoredPlaces(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	params := r.URL.Query()
+	q := store.Query{City: params.Get("city"), Limit: 100}
+	if types := params.Get("types"); types != "" {
+		q.Types = strings.Split(types, ",")
+	}
+	if params.Has("latitude") || params.Has("longitude") {
+		lat, err := strconv.ParseFloat(params.Get("latitude"), 64)
+		if err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+		long, err := strconv.ParseFloat(params.Get("longitude"), 64)
+		if err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+		radius, err := strconv.ParseFloat(params.Get("radius"), 64)
+		if err != nil || radius <= 0 {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a valid radius in meters"})
+			return
+		}
+		q.Near, q.Radius = &geo.Location{Latitude: lat, Longitude: long}, radius
+	}
+	if openAt := params.Get("openAt"); openAt != "" {
+		t, err := time.Parse(time.RFC3339, openAt)
+		if err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+		q.OpenAt = t
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a valid limit"})
+			return
+		}
+		q.Limit = n
+	}
+	results := s.store.Find(q)
+	exp.respond(w, results, storedItems(results...))
+}
+
+// Look up a stored place by placeId
+func (s *Server) GetStoredPlace(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	placeID := mux.Vars(r)["placeID"]
+	place, ok := s.store.Get(placeID)
+	if !ok {
+		responseJson(w, http.StatusNotFound, Response{Data: nil, Error: "Place not found in store"})
+		return
+	}
+	exp.respond(w, place, storedItems(store.Result{Record: place}))
+}
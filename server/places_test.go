@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing stored places are browsed by query params and looked up by ID
+func Test_GetStoredPlaces(t *testing.T) {
+	s := newTestServer(t, Config{}, nil)
+	s.savePlaces(
+		geo.Place{Id: "pizza", PrimaryType: "pizza_restaurant", Location: geo.Location{Latitude: 44.645, Longitude: -63.573}},
+		geo.Place{Id: "cafe", PrimaryType: "cafe", Location: geo.Location{Latitude: 44.648, Longitude: -63.570}},
+	)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/places?types=cafe,bar&latitude=44.6488&longitude=-63.5752&radius=1000", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []store.Result `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, "cafe", resp.Data[0].Id)
+	assert.NotNil(t, resp.Data[0].DistanceMeters)
+
+	for _, url := range []string{"/places?latitude=44.6&longitude=-63.5", "/places?openAt=tomorrow", "/places?limit=-1"} {
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/places/pizza", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/places/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// Testing stored places are exported in the negotiated format
+func Test_GetStoredPlaces_Export(t *testing.T) {
+	s := newTestServer(t, Config{}, nil)
+	s.savePlaces(geo.Place{Id: "cafe", DisplayName: geo.LocalizedText{Text: "Harbour Cafe"}, Location: geo.Location{Latitude: 44.648, Longitude: -63.57}})
+
+	w := httptest.NewRecorder()
+	s.GetStoredPlaces(w, httptest.NewRequest(http.MethodGet, "/places?format=geojson", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"geometry":{"type":"Point","coordinates":[-63.57,44.648]}`)
+
+	r := httptest.NewRequest(http.MethodGet, "/places?latitude=44.648&longitude=-63.57&radius=100&columns=id,name,distanceMeters", nil)
+	r.Header.Set("Accept", "text/csv")
+	w = httptest.NewRecorder()
+	s.GetStoredPlaces(w, r)
+	assert.Equal(t, "id,name,distanceMeters\ncafe,Harbour Cafe,0\n", w.Body.String())
+
+	for _, url := range []string{"/places?format=xml", "/places?format=csv&columns=id,secret"} {
+		w = httptest.NewRecorder()
+		s.GetStoredPlaces(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
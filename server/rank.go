@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/geolocate/geo"
+)
+
+// RankBy picks how search results are ordered by the server. Google's RankPreference only
+// offers distance and popularity, so results are re-ranked locally after fetching
+type RankBy string
+
+const (
+	RankByRelevance  = RankBy("")           // Keep Google's order
+	RankByDistance   = RankBy("distance")   // Nearest to the search center first
+	RankByRating     = RankBy("rating")     // Highest rating first
+	RankByPopularity = RankBy("popularity") // Highest rating weighted by log of the number of ratings first
+	RankByOpenNow    = RankBy("open_now")   // Open places first, otherwise Google's order
+)
+
+func (r RankBy) validate() error {
+	switch r {
+	case RankByRelevance, RankByDistance, RankByRating, RankByPopularity, RankByOpenNow:
+		return nil
+	}
+	return fmt.Errorf("Please enter a valid rankBy: %s, %s, %s or %s", RankByDistance, RankByRating, RankByPopularity, RankByOpenNow)
+}
+
+// Field masks a ranking needs on top of the defaults
+func (r RankBy) fieldMasks() []geo.PlaceFieldMask {
+	switch r {
+	case RankByRating:
+		return []geo.PlaceFieldMask{geo.PlaceFieldMaskRatings}
+	case RankByPopularity:
+		return []geo.PlaceFieldMask{geo.PlaceFieldMaskRatings, geo.PlaceFieldMaskUserRatingCount}
+	case RankByOpenNow:
+		return geo.OpeningHoursFieldMasks
+	}
+	return nil
+}
+
+// Rating weighted by how many users rated. A 4.5 with thousands of ratings beats a 5.0 with two
+func popularity(p geo.Place) float64 {
+	return p.Rating * math.Log1p(float64(p.UserRatingCount))
+}
+
+// Sort results in place. Ties keep Google's order
+func rankResults(results []PlaceResult, by RankBy, now time.Time) {
+	var less func(a, b PlaceResult) bool
+	switch by {
+	case RankByDistance:
+		less = func(a, b PlaceResult) bool {
+			if a.DistanceMeters == nil || b.DistanceMeters == nil {
+				return a.DistanceMeters != nil
+			}
+			return *a.DistanceMeters < *b.DistanceMeters
+		}
+	case RankByRating:
+		less = func(a, b PlaceResult) bool { return a.Rating > b.Rating }
+	case RankByPopularity:
+		less = func(a, b PlaceResult) bool { return popularity(a.Place) > popularity(b.Place) }
+	case RankByOpenNow:
+		open := make(map[string]bool, len(results))
+		for _, r := range results {
+			open[r.Id] = geo.PlaceOpenDuring(r.Place, now, time.Time{}) == geo.OpenStatusOpen
+		}
+		less = func(a, b PlaceResult) bool { return open[a.Id] && !open[b.Id] }
+	default:
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool { return less(results[i], results[j]) })
+}
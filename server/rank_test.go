@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func resultIDs(results []PlaceResult) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Id)
+	}
+	return ids
+}
+
+// Testing results are annotated with distance and bearing and re-ranked locally
+func Test_RankResults(t *testing.T) {
+	origin := &geo.Location{Latitude: 44.6488, Longitude: -63.5752}
+	open := geo.OpeningHours{Periods: []geo.Period{{Open: geo.Point{Day: 0}}}}
+	places := []geo.Place{
+		{Id: "far", Location: geo.Location{Latitude: 44.70, Longitude: -63.5752}, Rating: 4.9, UserRatingCount: 12},
+		{Id: "near", Location: geo.Location{Latitude: 44.65, Longitude: -63.5752}, Rating: 3.9, UserRatingCount: 400},
+		{Id: "mid", Location: geo.Location{Latitude: 44.6488, Longitude: -63.55}, Rating: 4.5, UserRatingCount: 2000, RegularOpeningHours: open},
+	}
+	tests := []struct {
+		rankBy RankBy
+		want   []string
+	}{
+		{RankByRelevance, []string{"far", "near", "mid"}},
+		{RankByDistance, []string{"near", "mid", "far"}},
+		{RankByRating, []string{"far", "mid", "near"}},
+		{RankByPopularity, []string{"mid", "near", "far"}},
+		{RankByOpenNow, []string{"mid", "far", "near"}},
+	}
+	for _, tt := range tests {
+		assert.NoError(t, tt.rankBy.validate())
+		result := newSearchResult(places, "", nil, origin)
+		rankResults(result.Places, tt.rankBy, time.Now())
+		assert.Equal(t, tt.want, resultIDs(result.Places), string(tt.rankBy))
+	}
+	assert.Error(t, RankBy("cheapest").validate())
+
+	result := newSearchResult(places, "", nil, origin)
+	assert.InDelta(t, 5693, *result.Places[0].DistanceMeters, 10)
+	assert.InDelta(t, 0, *result.Places[0].Bearing, 1e-6)
+	assert.InDelta(t, 90, *result.Places[2].Bearing, 0.1)
+	assert.Nil(t, newSearchResult(places, "", nil, nil).Places[0].DistanceMeters)
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+)
+
+// Report the refresher's progress. Stored places are refreshed in the background before Google's 30 day
+// caching deadline. Requires the X-Admin-Token header to match Config.AdminToken
+func (s *Server) GetRefreshStats(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		responseJson(w, http.StatusForbidden, Response{Data: nil, Error: "Admin token missing or invalid"})
+		return
+	}
+	if s.refresher == nil {
+		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: "Refresher is not running, API_KEY is not set"})
+		return
+	}
+	responseJson(w, http.StatusOK, Response{Data: s.refresher.Stats(), Error: ""})
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/geo/geometry"
+)
+
+// Search pages read at most when refilling a page that was thinned out by the open window filter
+var openFilterMaxPages = 3
+
+// OpenWindow asks for places open for the whole of From-To. Eg: bars open after 11pm Friday.
+// With Annotate every place is kept and marked open or closed instead of closed places being dropped
+type OpenWindow struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Annotate bool      `json:"annotate,omitempty"`
+}
+
+// PlaceResult is a place as returned by Google along with fields computed by the server
+type PlaceResult struct {
+	geo.Place
+	OpenDuring     geo.OpenStatus `json:"openDuring,omitempty"`     // Only set when the request had an open window
+	DistanceMeters *float64       `json:"distanceMeters,omitempty"` // From the search center
+	Bearing        *float64       `json:"bearing,omitempty"`        // Degrees clockwise from north, from the search center to the place
+}
+
+// SearchResult is the response to nearby and text searches
+type SearchResult struct {
+	Places        []PlaceResult `json:"places"`
+	NextPageToken string        `json:"nextPageToken,omitempty"`
+	Source        string        `json:"source,omitempty"` // "local" when served from stored places because Google refused the search
+}
+
+func (o *OpenWindow) validate() error {
+	if o.From.IsZero() {
+		return errors.New("Please enter a valid openDuring.from time")
+	}
+	if !o.To.IsZero() && o.To.Before(o.From) {
+		return errors.New("openDuring.to must not be before openDuring.from")
+	}
+	return nil
+}
+
+// Field masks for a search. Location is always requested for distances. Opening hours and time zone are added when
+// results are checked against an open window, and whatever else the ranking needs
+func searchFieldMask(window *OpenWindow, rankBy RankBy) []geo.PlaceFieldMask {
+	masks := append([]geo.PlaceFieldMask{}, defaultFieldMask...)
+	extra := append([]geo.PlaceFieldMask{geo.PlaceFieldMaskLocation}, rankBy.fieldMasks()...)
+	if window != nil {
+		extra = append(extra, geo.OpeningHoursFieldMasks...)
+	}
+	for _, mask := range extra {
+		if !slices.Contains(masks, mask) {
+			masks = append(masks, mask)
+		}
+	}
+	return masks
+}
+
+// Wrap places for the response with distance and bearing from origin, marking each against the open window when
+// one was requested. origin may be nil when the search had no center
+func newSearchResult(places []geo.Place, nextPageToken string, window *OpenWindow, origin *geo.Location) SearchResult {
+	result := SearchResult{Places: make([]PlaceResult, 0, len(places)), NextPageToken: nextPageToken}
+	for _, p := range places {
+		item := PlaceResult{Place: geo.WithPlusCode(p)}
+		if window != nil {
+			item.OpenDuring = geo.PlaceOpenDuring(p, window.From, window.To)
+		}
+		if origin != nil {
+			distance := geometry.Haversine(origin.Point(), p.Location.Point())
+			bearing := geometry.InitialBearing(origin.Point(), p.Location.Point())
+			item.DistanceMeters, item.Bearing = &distance, &bearing
+		}
+		result.Places = append(result.Places, item)
+	}
+	return result
+}
+
+// Search center from request coordinates. Zero coordinates mean none was given
+func searchOrigin(lat, long float64) *geo.Location {
+	if lat == 0 && long == 0 {
+		return nil
+	}
+	return &geo.Location{Latitude: lat, Longitude: long}
+}
+
+// Full plus code for a user supplied code. Short codes are recovered near lat,long, which must be given
+func recoverPlusCode(code string, lat, long float64) (string, error) {
+	if geo.IsFullPlusCode(code) {
+		return strings.ToUpper(code), nil
+	}
+	if !geo.IsShortPlusCode(code) {
+		return "", geo.ErrInvalidPlusCode
+	}
+	if searchOrigin(lat, long) == nil {
+		return "", errors.New("short plus code needs latitude and longitude of a nearby reference location")
+	}
+	return geo.RecoverNearestPlusCode(code, lat, long)
+}
@@ -0,0 +1,38 @@
+package server
+
+import "github.com/gorilla/mux"
+
+func (s *Server) routes() *mux.Router {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/getplace/{placeID}", s.GetPlacebyId).Methods("GET")
+	r.HandleFunc("/places:batchGet", s.GetPlacesBatch).Methods("POST")
+	r.HandleFunc("/geocode", s.GetGeocode).Methods("GET")
+	r.HandleFunc("/geocode:batch", s.GetGeocodeBatch).Methods("POST")
+	r.HandleFunc("/geodecode", s.GetGeodecode).Methods("GET")
+	r.HandleFunc("/isopen/{placeID}", s.GetPlaceisOpen).Methods("GET")
+	r.HandleFunc("/nearbysearch", s.GetPlacesNearby).Methods("POST")
+	r.HandleFunc("/textsearch", s.GetPlacesFromText).Methods("POST")
+	r.HandleFunc("/searchalongroute", s.GetPlacesAlongRoute).Methods("POST")
+	r.HandleFunc("/boundedtextsearch", s.GetPlacesBoundedText).Methods("POST")
+
+	r.HandleFunc("/places", s.GetStoredPlaces).Methods("GET")
+	r.HandleFunc("/places/{placeID}", s.GetStoredPlace).Methods("GET")
+	r.HandleFunc("/admin/cache", s.PurgeCache).Methods("DELETE")
+	r.HandleFunc("/admin/refresh", s.GetRefreshStats).Methods("GET")
+	r.HandleFunc("/admin/deadletters", s.GetDeadLetters).Methods("GET")
+
+	r.HandleFunc("/watchlists", s.CreateWatchlist).Methods("POST")
+	r.HandleFunc("/watchlists", s.GetWatchlists).Methods("GET")
+	r.HandleFunc("/watchlists/{watchlistID}", s.GetWatchlist).Methods("GET")
+	r.HandleFunc("/watchlists/{watchlistID}", s.DeleteWatchlist).Methods("DELETE")
+
+	r.HandleFunc("/jobs", s.SubmitJob).Methods("POST")
+	r.HandleFunc("/jobs", s.GetJobs).Methods("GET")
+	r.HandleFunc("/jobs/{jobID}", s.GetJob).Methods("GET")
+	r.HandleFunc("/jobs/{jobID}/results", s.GetJobResults).Methods("GET")
+
+	r.HandleFunc("/types", GetAllTypes).Methods("GET")
+	r.HandleFunc("/defaulttypes", GetDefaultTypes).Methods("GET")
+	return r
+}
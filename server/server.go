@@ -3,27 +3,137 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/geolocate/cache"
 	"github.com/geolocate/client"
+	"github.com/geolocate/export"
 	"github.com/geolocate/geo"
+	"github.com/geolocate/jobs"
+	"github.com/geolocate/store"
+	"github.com/geolocate/watch"
 	"github.com/gorilla/mux"
 )
 
-var apiKey = os.Getenv("API_KEY")
-var defaultFieldMask = []geo.PlaceFieldMask{geo.PlaceFieldMaskBusinessStatus, geo.PlaceFieldMaskFormattedAddress, geo.PlaceFieldMaskDispName, geo.PlaceFieldMaskPlaceID, geo.PlaceFieldMaskTypes, geo.PlaceFieldMaskOpeningHours}
+// Server answers the REST API. It holds one Google client, so every request shares its rate limiter,
+// along with the response cache, the place store and the background services built on them
+type Server struct {
+	config     Config
+	client     *geo.GeoClient // Nil without an API key
+	cache      *cache.Cache
+	store      *store.Store
+	localities *localityCache
+	refresher  *store.Refresher // Nil without an API key, as are the watcher and jobs
+	watcher    *watch.Watcher
+	jobs       *jobs.Manager
+	logger     *log.Logger
+	router     *mux.Router
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithGeoClient calls Google through c instead of a client built from Config.APIKey. Eg: one pointed at a test server
+func WithGeoClient(c *geo.GeoClient) Option {
+	return func(s *Server) {
+		s.client = c
+	}
+}
+
+// WithCache serves upstream responses through c instead of a cache built from the config
+func WithCache(c *cache.Cache) Option {
+	return func(s *Server) {
+		s.cache = c
+	}
+}
+
+// WithLogger logs to l instead of the standard logger
+func WithLogger(l *log.Logger) Option {
+	return func(s *Server) {
+		s.logger = l
+	}
+}
+
+// New builds a server and its routes. Background services are started with Start
+func New(config Config, opts ...Option) (*Server, error) {
+	s := &Server{config: config, localities: newLocalityCache()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		s.logger = log.Default()
+	}
+	if s.client == nil && config.APIKey != "" {
+		c, err := client.NewClient(client.AddAPIKey(config.APIKey))
+		if err != nil {
+			return nil, err
+		}
+		s.client = &geo.GeoClient{Client: c}
+	}
+	if s.cache == nil {
+		s.cache = s.newResponseCache()
+	}
+	s.store = s.openPlaceStore()
+	if s.client != nil {
+		s.refresher = store.NewRefresher(s.store, s.client, store.RealClock, store.RefreshOptions{Logger: s.logger})
+		s.watcher = s.newWatcher()
+		s.jobs = s.newJobs()
+	}
+	s.router = s.routes()
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// Start sweeps the cache, refreshes stored places and checks watchlists in the background until ctx is done
+func (s *Server) Start(ctx context.Context) {
+	go s.cache.SweepEvery(ctx, time.Hour)
+	if s.refresher != nil {
+		go s.refresher.Run(ctx)
+	}
+	if s.watcher != nil {
+		go s.watcher.Run(ctx)
+	}
+}
+
+// Close stops running jobs and closes the place store
+func (s *Server) Close() error {
+	if s.jobs != nil {
+		s.jobs.Close()
+	}
+	return s.store.Close()
+}
+
+// Routes calling Google respond 503 when there is no API key
+func (s *Server) clientReady(w http.ResponseWriter) bool {
+	if s.client == nil {
+		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: "API_KEY is not set"})
+		return false
+	}
+	return true
+}
+
+var defaultFieldMask = []geo.PlaceFieldMask{geo.PlaceFieldMaskBusinessStatus, geo.PlaceFieldMaskFormattedAddress, geo.PlaceFieldMaskDispName, geo.PlaceFieldMaskPlaceID, geo.PlaceFieldMaskTypes, geo.PlaceFieldMaskOpeningHours, geo.PlaceFieldMaskPrimaryType, geo.PlaceFieldMaskAddressComponents}
+var hoursFieldMask = []geo.PlaceFieldMask{geo.PlaceFieldMaskPlaceID, geo.PlaceFieldMaskOpeningHours, geo.PlaceFieldMaskCurrentOpeningHours, geo.PlaceFieldMaskSecondaryHours, geo.PlaceFieldMaskTimezone, geo.PlaceFieldMaskUtcOffset}
 var resultCount = int32(10)
 var searchString = "in"
 
 // Look up  Geocoded Map input with lat,long and fetch a human readable address metadata
 
-func GetGeodecode(w http.ResponseWriter, r *http.Request) {
-	c, err := client.NewClient(client.AddAPIKey(apiKey))
-	if err != nil {
-		responseJson(w, http.StatusServiceUnavailable, Response{Error: err.Error()})
-
+func (s *Server) GetGeodecode(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	if !s.clientReady(w) {
+		return
 	}
 	queryParams := r.URL.Query()
 	lat, err := strconv.ParseFloat(queryParams.Get("latitude"), 64)
@@ -36,46 +146,69 @@ func GetGeodecode(w http.ResponseWriter, r *http.Request) {
 		responseJson(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
-	apiClient := geo.GeoClient{c}
-	ctx := context.Background()
+	ctx := r.Context()
 	req := geo.GeocodingRequest{LatLng: &geo.LatLng{Lat: lat, Lng: long}}
-	geodecode, err := apiClient.Geodecode(ctx, &req)
+	geodecode, err := cached(w, ctx, s.cache, cache.Key("geodecode", req), func(ctx context.Context) (geo.GeocodingResponse, error) {
+		return s.client.Geodecode(ctx, &req)
+	})
 	if err != nil {
 		responseJson(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
-	responseJson(w, http.StatusOK, Response{Data: geodecode, Error: ""}) // Success
+	exp.respond(w, geodecode, geocodeItems(geodecode.Results...))
 
 }
 
 // Look up a human readable address to get Geocoded Map response with lat,long and other geometric detail
-func GetGeocode(w http.ResponseWriter, r *http.Request) {
-	c, err := client.NewClient(client.AddAPIKey(apiKey))
-	if err != nil {
-		responseJson(w, http.StatusServiceUnavailable, Response{Error: err.Error()})
+func (s *Server) GetGeocode(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
 		return
 	}
 	queryParams := r.URL.Query()
-	placeAddress := queryParams.Get("address")
-	apiClient := geo.GeoClient{c}
-	ctx := context.Background()
+	placeAddress := strings.TrimSpace(queryParams.Get("address"))
+	if geo.IsValidPlusCode(placeAddress) { // Plus codes are decoded locally without calling the Geocoding API
+		lat, _ := strconv.ParseFloat(queryParams.Get("latitude"), 64)
+		long, _ := strconv.ParseFloat(queryParams.Get("longitude"), 64)
+		code, err := recoverPlusCode(placeAddress, lat, long)
+		if err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Error: err.Error()})
+			return
+		}
+		geocode, err := geo.GeocodePlusCode(code)
+		if err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Error: err.Error()})
+			return
+		}
+		exp.respond(w, geocode, geocodeItems(geocode.Results...))
+		return
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	ctx := r.Context()
 	req := geo.GeocodingRequest{Address: placeAddress}
 	// fmt.Printf("%+v/n", req)
-	geocode, err := apiClient.Geocode(ctx, &req)
+	geocode, err := cached(w, ctx, s.cache, cache.Key("geocode", req), func(ctx context.Context) (geo.GeocodingResponse, error) {
+		return s.client.Geocode(ctx, &req)
+	})
 	if err != nil {
 		responseJson(w, http.StatusBadRequest, Response{Error: err.Error()})
 		return
 	}
-	responseJson(w, http.StatusOK, Response{Data: geocode}) // Success
+	exp.respond(w, geocode, geocodeItems(geocode.Results...))
 
 }
 
 // Define a struct to match the expected JSON body
 type PlacesNearby struct {
-	Lat    float64  `json:"latitude"`
-	Long   float64  `json:"longitude"`
-	Radius int64    `json:"radius"`
-	Types  []string `json:"types"`
+	Lat        float64     `json:"latitude"`
+	Long       float64     `json:"longitude"`
+	Radius     int64       `json:"radius"`
+	Types      []string    `json:"types"`
+	OpenDuring *OpenWindow `json:"openDuring,omitempty"` // Only return places open during this window
+	RankBy     RankBy      `json:"rankBy,omitempty"`     // Re-rank results: distance, rating, popularity or open_now
+	PlusCode   string      `json:"plusCode,omitempty"`   // Search around this plus code instead of latitude,longitude. Short codes are recovered near latitude,longitude
 }
 
 // Define a struct to match the expected JSON body
@@ -84,12 +217,21 @@ type PlacesFromText struct {
 	Long      float64 `json:"longitude"`
 	Radius    int64   `json:"radius"`
 	Text      string  `json:"text,omitempty"`
-	Locality  string  `json:"locality"`            // We need to get this in front end from user's lat,long and send it in their text search request. Eg: locality="Boston MA, USA". We append this to the Text( eg Skating Ring). So we limit the search to the city
+	Locality  string  `json:"locality"`            // User's city Eg: locality="Boston MA, USA". We append this to the Text( eg Skating Ring) to limit the search to the city. Resolved from latitude,longitude when empty
 	PageToken string  `json:"pageToken,omitempty"` // Paginated results
+	// Only return places open during this window. Further pages are fetched to fill the page with open places
+	OpenDuring *OpenWindow `json:"openDuring,omitempty"`
+	RankBy     RankBy      `json:"rankBy,omitempty"` // Re-rank results: distance, rating, popularity or open_now
+	// Restrict results to the viewport of the user's city instead of naming the city in the query
+	RestrictToLocality bool `json:"restrictToLocality,omitempty"`
 }
 
 // Find Places Nearby a user. Filter out places using incTypes to get results that match user preferences
-func GetPlacesNearby(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetPlacesNearby(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
 	var params PlacesNearby
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
@@ -97,33 +239,99 @@ func GetPlacesNearby(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	incTypes := geo.GetDefaultPlacesTypes()
-	if len(params.Types) > 0 { // if we got a valid Type from user. Need to lookup Type A and B table to find match.TODO
-		var placeTypes []geo.PlaceType
-		for _, t := range params.Types {
-			placeTypes = append(placeTypes, geo.PlaceType(t))
+	if len(params.Types) > 0 { // Map user's free form types to valid place types. Reject unknown ones with suggestions instead of letting the upstream call fail
+		placeTypes, unknown := geo.ResolvePlaceTypes(params.Types)
+		if len(unknown) > 0 {
+			responseJson(w, http.StatusBadRequest, Response{Data: unknown, Error: unknown[0].Error()})
+			return
+		}
+		incTypes = placeTypes
+	}
+	if params.OpenDuring != nil {
+		if err := params.OpenDuring.validate(); err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+	}
+	if err := params.RankBy.validate(); err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	if params.Radius > geo.MaxNearbyRadius {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: fmt.Sprintf("radius must be at most %d meters", geo.MaxNearbyRadius)})
+		return
+	}
+	stream := wantsStream(r)
+	if stream && params.RankBy != RankByRelevance {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "rankBy is not supported when streaming, places are sent as they are found"})
+		return
+	}
+	if stream && exp.format != export.FormatJSON {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "format is not supported when streaming, places are sent as newline delimited JSON"})
+		return
+	}
+	if params.PlusCode != "" {
+		code, err := recoverPlusCode(params.PlusCode, params.Lat, params.Long)
+		if err == nil {
+			var area geo.CodeArea
+			if area, err = geo.DecodePlusCode(code); err == nil {
+				center := area.Center()
+				params.Lat, params.Long = center.Latitude, center.Longitude
+			}
+		}
+		if err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
 		}
-		incTypes = placeTypes // If place types are invalid, then we will have a problem. Need to think this further.TODO
 	}
 	location := geo.LocationRestriction{Circle: geo.Circle{Center: geo.Location{Latitude: params.Lat, Longitude: params.Long}, Radius: params.Radius}}
 	req := geo.NearbySearchRequest{LocationRestriction: &location, MaxResultCount: resultCount, IncludedTypes: incTypes}
-	c, err := client.NewClient(client.AddAPIKey(apiKey))
-	if err != nil {
-		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
+	filterOpen := params.OpenDuring != nil && !params.OpenDuring.Annotate
+	if filterOpen || params.RankBy != RankByRelevance {
+		req.MaxResultCount = 20 // Nearby search has no pages. Ask for as many candidates as allowed before dropping closed places or re-ranking
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	ctx := r.Context()
+	header := geo.PlacesHeader{FieldMasks: searchFieldMask(params.OpenDuring, params.RankBy), FieldMaskPrefix: true}
+	if stream {
+		req.MaxResultCount = 20 // Cells returning a full page are split, so ask for as many as allowed
+		s.streamNearbySearch(w, r, req, header, params)
 		return
 	}
-	apiClient := geo.GeoClient{c}
-	ctx := context.Background()
-	header := geo.PlacesHeader{FieldMasks: defaultFieldMask, FieldMaskPrefix: true}
-	place, err := apiClient.NearbySearch(ctx, &req, &header) //TODO
+	place, err := cached(w, ctx, s.cache, cache.Key("nearbysearch", req, header), func(ctx context.Context) (geo.PlacesSearchResponse, error) {
+		resp, err := s.client.NearbySearch(ctx, &req, &header)
+		s.saveFetched(err, resp.Places...)
+		return resp, err
+	})
+	source := ""
+	if s.useLocalFallback(err) {
+		place, source, err = s.localNearby(params, incTypes, int(req.MaxResultCount)), sourceLocal, nil
+	}
 	if err != nil {
 		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
 		return
 	}
-	responseJson(w, http.StatusOK, Response{Data: place, Error: ""}) // Success
+	places := place.Places
+	if filterOpen {
+		places = geo.FilterOpenDuring(places, params.OpenDuring.From, params.OpenDuring.To)
+	}
+	result := newSearchResult(places, place.NextPageToken, params.OpenDuring, searchOrigin(params.Lat, params.Long))
+	result.Source = source
+	rankResults(result.Places, params.RankBy, time.Now())
+	if len(result.Places) > int(resultCount) {
+		result.Places = result.Places[:resultCount]
+	}
+	exp.respond(w, result, searchItems(result))
 }
 
 // Find Places from Text. Locality represents user's current city,province,country as string
-func GetPlacesFromText(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetPlacesFromText(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
 	var params PlacesFromText
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
@@ -134,28 +342,200 @@ func GetPlacesFromText(w http.ResponseWriter, r *http.Request) {
 		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a valid search text"})
 		return
 	}
-	textQuery := params.Text + searchString + params.Locality
+	if params.OpenDuring != nil {
+		if err := params.OpenDuring.validate(); err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+	}
+	if err := params.RankBy.validate(); err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	stream := wantsStream(r)
+	if stream && params.RankBy != RankByRelevance {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "rankBy is not supported when streaming, places are sent as they are found"})
+		return
+	}
+	if stream && exp.format != export.FormatJSON {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "format is not supported when streaming, places are sent as newline delimited JSON"})
+		return
+	}
+	origin := searchOrigin(params.Lat, params.Long)
+	if params.RestrictToLocality && origin == nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "latitude and longitude are required to restrict results to the locality"})
+		return
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	ctx := r.Context()
 	locationBias := geo.LocationRestriction{Circle: geo.Circle{Center: geo.Location{Latitude: params.Lat, Longitude: params.Long}, Radius: params.Radius}}
-	req := geo.TextSearchRequest{TextQuery: textQuery, LocationBias: &locationBias, RankPreference: geo.RankPreferenceDistance, PageSize: resultCount, PageToken: params.PageToken}
-	c, err := client.NewClient(client.AddAPIKey(apiKey))
+	req := geo.TextSearchRequest{TextQuery: localityQuery(params.Text, params.Locality), LocationBias: &locationBias, RankPreference: geo.RankPreferenceDistance, PageSize: resultCount, PageToken: params.PageToken}
+	var viewport *geo.LatLngBounds // Locality to restrict results to
+	if params.RestrictToLocality || (params.Locality == "" && origin != nil) {
+		locality, err := s.resolveLocality(ctx, params.Lat, params.Long)
+		switch {
+		case err != nil && params.RestrictToLocality && !s.useLocalFallback(err):
+			responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
+			return
+		case err != nil:
+			// Without a locality the search is still biased to the user's location. Quota errors will
+			// likely fail the search too, which then falls back to stored places
+		case params.RestrictToLocality:
+			// Google rejects a request with both a bias and a restriction
+			req.LocationBias = nil
+			req.TextQuery = localityQuery(params.Text, "")
+			bounds := locality.bounds()
+			viewport = &bounds
+		default:
+			req.TextQuery = localityQuery(params.Text, locality.Locality)
+		}
+	}
+	header := geo.PlacesHeader{FieldMasks: searchFieldMask(params.OpenDuring, params.RankBy), FieldMaskPrefix: true, TokenMask: geo.MaskNextPageToken}
+	if stream {
+		if viewport != nil {
+			req.LocationRestriction = &geo.RectangularRestriction{Rectangle: viewport.Rectangle()} // Pages are streamed from a single search
+		}
+		s.streamTextSearch(w, r, req, header, params)
+		return
+	}
+	filterOpen := params.OpenDuring != nil && !params.OpenDuring.Annotate
+	key := cache.Key("textsearch", req, header, viewport)
+	if filterOpen {
+		key = cache.Key("textsearch", req, header, viewport, params.OpenDuring.From, params.OpenDuring.To)
+	}
+	place, err := cached(w, ctx, s.cache, key, func(ctx context.Context) (geo.PlacesSearchResponse, error) {
+		var resp geo.PlacesSearchResponse
+		var err error
+		switch {
+		case viewport != nil:
+			// Searched the way /boundedtextsearch is, so large viewports and ones crossing the antimeridian
+			// are split and places outside the city are dropped
+			resp, err = s.client.TextSearchInViewport(ctx, *viewport, &req, &header)
+			if filterOpen {
+				resp.Places = geo.FilterOpenDuring(resp.Places, params.OpenDuring.From, params.OpenDuring.To)
+			}
+		case filterOpen:
+			resp, err = s.client.TextSearchOpenDuring(ctx, &req, &header, params.OpenDuring.From, params.OpenDuring.To, openFilterMaxPages)
+		default:
+			resp, err = s.client.TextSearch(ctx, &req, &header)
+		}
+		s.saveFetched(err, resp.Places...)
+		return resp, err
+	})
+	source := ""
+	if s.useLocalFallback(err) {
+		place, source, err = s.localText(params, filterOpen), sourceLocal, nil
+	}
 	if err != nil {
 		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
 		return
 	}
-	apiClient := geo.GeoClient{c}
-	ctx := context.Background()
-	header := geo.PlacesHeader{FieldMasks: defaultFieldMask, FieldMaskPrefix: true, TokenMask: geo.MaskNextPageToken}
-	place, err := apiClient.TextSearch(ctx, &req, &header)
+	result := newSearchResult(place.Places, place.NextPageToken, params.OpenDuring, origin)
+	result.Source = source
+	rankResults(result.Places, params.RankBy, time.Now())
+	if len(result.Places) > int(resultCount) {
+		result.Places = result.Places[:resultCount] // Open filtered pages are cut to size with a page token resuming after them
+	}
+	exp.respond(w, result, searchItems(result))
+}
+
+// Define a struct to match the expected JSON body
+type PlacesAlongRoute struct {
+	Text              string  `json:"text"`
+	EncodedPolyline   string  `json:"encodedPolyline"`    // Route as returned by Google Routes API. Eg: the user's drive
+	Lat               float64 `json:"latitude,omitempty"` // Optional origin of the trip. Defaults to start of the route
+	Long              float64 `json:"longitude,omitempty"`
+	TravelMode        string  `json:"travelMode,omitempty"`        // DRIVE, BICYCLE, WALK or TWO_WHEELER. Defaults to DRIVE
+	RoutingPreference string  `json:"routingPreference,omitempty"` // TRAFFIC_UNAWARE, TRAFFIC_AWARE or TRAFFIC_AWARE_OPTIMAL
+}
+
+// Routing parameters for the search, rejecting values Google would refuse with an opaque error
+func (p PlacesAlongRoute) routing() (geo.RoutingParameters, error) {
+	routing := geo.RoutingParameters{TravelMode: geo.TravelModeDrive, RoutingPreference: geo.RoutingPreference(p.RoutingPreference)}
+	if p.TravelMode != "" {
+		routing.TravelMode = geo.TravelMode(p.TravelMode)
+	}
+	switch routing.TravelMode {
+	case geo.TravelModeDrive, geo.TravelModeTwoWheeler:
+	case geo.TravelModeBicycle, geo.TravelModeWalk:
+		if routing.RoutingPreference != "" {
+			return routing, fmt.Errorf("routingPreference is only supported for %s and %s", geo.TravelModeDrive, geo.TravelModeTwoWheeler)
+		}
+	default:
+		return routing, fmt.Errorf("Please enter a valid travelMode: %s, %s, %s or %s", geo.TravelModeDrive, geo.TravelModeBicycle, geo.TravelModeWalk, geo.TravelModeTwoWheeler)
+	}
+	switch routing.RoutingPreference {
+	case "", geo.RoutingPreferenceTrafficUnaware, geo.RoutingPreferenceTrafficAware, geo.RoutingPreferenceTrafficAwareOptimal:
+	default:
+		return routing, fmt.Errorf("Please enter a valid routingPreference: %s, %s or %s", geo.RoutingPreferenceTrafficUnaware, geo.RoutingPreferenceTrafficAware, geo.RoutingPreferenceTrafficAwareOptimal)
+	}
+	if p.Lat != 0 || p.Long != 0 {
+		routing.Origin = &geo.Location{Latitude: p.Lat, Longitude: p.Long}
+	}
+	return routing, nil
+}
+
+// Find places matching search text along a route. Eg: coffee along my drive. Each place comes with a routing summary
+// giving the detour from origin to the place and on to the end of the route
+func (s *Server) GetPlacesAlongRoute(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	var params PlacesAlongRoute
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	if params.Text == "" || params.EncodedPolyline == "" {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a valid search text and route"})
+		return
+	}
+	routing, err := params.routing()
+	if err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	req := geo.TextSearchRequest{
+		TextQuery:                  params.Text,
+		PageSize:                   resultCount,
+		SearchAlongRouteParameters: &geo.SearchAlongRouteParameters{Polyline: geo.Polyline{EncodedPolyline: params.EncodedPolyline}},
+		RoutingParameters:          &routing,
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	ctx := r.Context()
+	header := geo.PlacesHeader{FieldMasks: defaultFieldMask, FieldMaskPrefix: true, ResponseMasks: []string{geo.MaskRoutingSummaries}}
+	place, err := s.client.TextSearch(ctx, &req, &header)
 	if err != nil {
 		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
 		return
 	}
-	responseJson(w, http.StatusOK, Response{Data: place, Error: ""})
+	s.savePlaces(place.Places...)
+	exp.respond(w, place, placeItems(place.Places...))
 }
 
-// Find places using search text within a given region using locationRestriction that match user preferences. WIP
-func GetPlacesBoundedText(w http.ResponseWriter, r *http.Request) {
-	var params PlacesFromText
+// Define a struct to match the expected JSON body
+type PlacesInRegion struct {
+	Text      string  `json:"text"`
+	Region    string  `json:"region,omitempty"`    // City or region to search within. Eg: "Halifax, NS, Canada"
+	Lat       float64 `json:"latitude,omitempty"`  // Search within the city containing latitude,longitude when region is empty
+	Long      float64 `json:"longitude,omitempty"` //
+	PageToken string  `json:"pageToken,omitempty"` // Paginated results. Only returned for regions searched in one request
+}
+
+// Find places using search text within a city or region. The region is geocoded and its viewport used as the
+// locationRestriction, so only places inside it are returned
+func (s *Server) GetPlacesBoundedText(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	var params PlacesInRegion
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
 		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
@@ -165,52 +545,142 @@ func GetPlacesBoundedText(w http.ResponseWriter, r *http.Request) {
 		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a valid search text"})
 		return
 	}
-	textQuery := params.Text
-	locationRestriction := geo.RectangularRestriction{Rectangle: geo.Rectangle{Low: geo.Location{Latitude: params.Lat, Longitude: params.Long}, High: geo.Location{Latitude: params.Lat, Longitude: params.Long}}} // Need to set this making call to Geodecode with a City + State+Country string. Low and High must be mapped correctly. TODO
-	req := geo.TextSearchRequest{TextQuery: textQuery, LocationRestriction: &locationRestriction, RankPreference: geo.RankPreferenceDistance, PageSize: resultCount, PageToken: params.PageToken}
-	c, err := client.NewClient(client.AddAPIKey(apiKey))
+	origin := searchOrigin(params.Lat, params.Long)
+	if params.Region == "" && origin == nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a region or latitude and longitude to search within"})
+		return
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	ctx := r.Context()
+	viewport, err := s.regionViewport(ctx, params.Region, params.Lat, params.Long)
 	if err != nil {
-		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
 		return
 	}
-	apiClient := geo.GeoClient{c}
-	ctx := context.Background()
+	req := geo.TextSearchRequest{TextQuery: params.Text, PageSize: resultCount, PageToken: params.PageToken}
 	header := geo.PlacesHeader{FieldMasks: defaultFieldMask, FieldMaskPrefix: true, TokenMask: geo.MaskNextPageToken}
-	place, err := apiClient.TextSearch(ctx, &req, &header) //TODO
+	place, err := s.client.TextSearchInViewport(ctx, viewport, &req, &header)
 	if err != nil {
 		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
 		return
 	}
-	responseJson(w, http.StatusOK, Response{Data: place, Error: ""}) // Success
+	s.savePlaces(place.Places...)
+	result := newSearchResult(place.Places, place.NextPageToken, nil, origin)
+	exp.respond(w, result, searchItems(result))
 }
 
 // Lookup a placeId to get all details of the place
-func GetPlacebyId(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetPlacebyId(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
 	vars := mux.Vars(r)
 	placeID := vars["placeID"]
 	if placeID == "" {
 		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a valid placeId"})
 		return
 	}
-	c, err := client.NewClient(client.AddAPIKey(apiKey))
-	if err != nil {
-		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
+	if !s.clientReady(w) {
 		return
 	}
-	apiClient := geo.GeoClient{c}
-	ctx := context.Background()
+	ctx := r.Context()
 	header := geo.PlacesHeader{FieldMasks: defaultFieldMask, FieldMaskPrefix: false}
-	place, err := apiClient.PlaceDetails(ctx, placeID, &header)
+	place, err := cached(w, ctx, s.cache, cache.Key("place", placeID, header), func(ctx context.Context) (geo.Place, error) {
+		place, err := s.client.PlaceDetails(ctx, placeID, &header)
+		s.saveFetched(err, place)
+		return place, err
+	})
 	if err != nil {
 		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
 		return
 	}
-	responseJson(w, http.StatusOK, Response{Data: place, Error: ""}) // Success
+	place = geo.WithPlusCode(place)
+	exp.respond(w, place, placeItems(place))
+}
+
+// Response for a place's opening hours check. Times are in the place's local time zone
+type PlaceOpen struct {
+	PlaceID   string     `json:"placeId"`
+	Open      bool       `json:"open"` // Open at from, or for the whole of from-to when to is given
+	From      time.Time  `json:"from"`
+	To        *time.Time `json:"to,omitempty"`
+	NextOpen  *time.Time `json:"nextOpen,omitempty"`
+	NextClose *time.Time `json:"nextClose,omitempty"`
+	Timezone  string     `json:"timeZone,omitempty"`
 }
 
-// Function to if check place is Open during the date,time specified in request
-func GetPlaceisOpen() {
-	// TODO. We try to reconsile user passed date time range against regularOpenHours for the placeID reported by Google
+// Check if a place is open at a time or during a time range. from and to are RFC3339 query params, from defaults to now.
+// secondary picks secondary hours instead of the main hours. Eg: secondary=DRIVE_THROUGH
+func (s *Server) GetPlaceisOpen(w http.ResponseWriter, r *http.Request) {
+	exp, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	placeID := mux.Vars(r)["placeID"]
+	if placeID == "" {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "Please enter a valid placeId"})
+		return
+	}
+	queryParams := r.URL.Query()
+	from := time.Now()
+	var err error
+	if v := queryParams.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+	}
+	var to *time.Time
+	if v := queryParams.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+			return
+		}
+		if t.Before(from) {
+			responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: "to must not be before from"})
+			return
+		}
+		to = &t
+	}
+	if !s.clientReady(w) {
+		return
+	}
+	ctx := r.Context()
+	header := geo.PlacesHeader{FieldMasks: hoursFieldMask, FieldMaskPrefix: false}
+	place, err := cached(w, ctx, s.cache, cache.Key("place", placeID, header), func(ctx context.Context) (geo.Place, error) {
+		return s.client.PlaceDetails(ctx, placeID, &header)
+	})
+	if err != nil {
+		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	schedule, err := geo.PlaceSchedule(place, geo.SecondaryHoursType(queryParams.Get("secondary")))
+	if err != nil {
+		responseJson(w, http.StatusNotFound, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	loc := geo.PlaceLocation(place)
+	result := PlaceOpen{PlaceID: place.Id, Open: schedule.OpenAt(from), From: from.In(loc), Timezone: place.Timezone.Id}
+	if to != nil {
+		result.Open = schedule.OpenDuring(from, *to)
+		localTo := to.In(loc)
+		result.To = &localTo
+	}
+	if t, ok := schedule.NextOpen(from); ok {
+		result.NextOpen = &t
+	}
+	if t, ok := schedule.NextClose(from); ok {
+		result.NextClose = &t
+	}
+	status := geo.OpenStatusClosed // Exported like the openDuring of search results
+	if result.Open {
+		status = geo.OpenStatusOpen
+	}
+	exp.respond(w, result, []export.Item{{Place: place, Properties: []export.Property{{Name: "openDuring", Value: status}}}})
 }
 func GetAllTypes(w http.ResponseWriter, r *http.Request) {
 	placeTypes := geo.GetAllPlacesTypes()
@@ -1,7 +1,246 @@
 package server
 
-// func Test_Server_GetPlacebyId(t *testing.T) {
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
 
-// 	var w http.ResponseWriter
-// 	var r *http.Request
-// }
+	"github.com/geolocate/client"
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// Server calling a fake Google API. A nil handler leaves it without a client, as when API_KEY is not set
+func newTestServer(t *testing.T, config Config, handler http.HandlerFunc) *Server {
+	var opts []Option
+	if handler != nil {
+		upstream := httptest.NewServer(handler)
+		t.Cleanup(upstream.Close)
+		c, err := client.NewClient(client.AddAPIKey("test"), client.WithBaseURL(upstream.URL), client.WithRateLimit(0))
+		assert.NoError(t, err)
+		opts = append(opts, WithGeoClient(&geo.GeoClient{Client: c}))
+	}
+	if config.JobsDir == "" {
+		config.JobsDir = t.TempDir()
+	}
+	s, err := New(config, opts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// Testing a place is looked up through the router with the injected client, then served from the cache and the store.
+// Only the fetch from Google is stored
+func Test_Server_GetPlacebyId(t *testing.T) {
+	calls := 0
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/v1/places/halifax", r.URL.Path)
+		assert.Equal(t, "test", r.Header.Get("X-Goog-Api-Key"))
+		fmt.Fprint(w, `{"id": "halifax", "displayName": {"text": "Halifax Central Library"}, "location": {"latitude": 44.6427, "longitude": -63.5754}}`)
+	})
+
+	for _, status := range []string{"MISS", "HIT"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/getplace/halifax", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, status, w.Header().Get("X-Cache"))
+		assert.Contains(t, w.Body.String(), `"plusCode"`)
+	}
+	assert.Equal(t, 1, calls)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/places/halifax", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Cached content is not stored again, or it would be dated as fetched now
+	assert.NoError(t, s.store.Delete("halifax"))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/getplace/halifax", nil))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/places:batchGet", strings.NewReader(`{"placeIds": ["halifax"]}`)))
+	assert.Equal(t, 1, calls)
+	_, ok := s.store.Get("halifax")
+	assert.False(t, ok)
+}
+
+// Testing routes calling Google respond 503 without an API key, while local ones still work
+func Test_Server_NoAPIKey(t *testing.T) {
+	s := newTestServer(t, Config{}, nil)
+	assert.Nil(t, s.refresher)
+	assert.Nil(t, s.jobs)
+	for url, want := range map[string]int{
+		"/geocode?address=Halifax":       http.StatusServiceUnavailable,
+		"/geocode?address=8FVC2222%2B22": http.StatusOK,
+		"/getplace/halifax":              http.StatusServiceUnavailable,
+		"/types":                         http.StatusOK,
+		"/admin/refresh":                 http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, want, w.Code, url)
+	}
+}
+
+// Testing a text search filtered to open places returns one page of results and a token resuming after it
+func Test_Server_TextSearchOpenDuring(t *testing.T) {
+	var tokens []string
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		var req geo.TextSearchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		tokens = append(tokens, req.PageToken)
+		var places []string
+		for i := range 12 {
+			places = append(places, fmt.Sprintf(`{"id": "p%d", "regularOpeningHours": {"periods": [{"open": {"day": 0}}]}}`, i))
+		}
+		fmt.Fprintf(w, `{"places": [%s]}`, strings.Join(places, ","))
+	})
+	search := func(pageToken string) SearchResult {
+		body := fmt.Sprintf(`{"text": "pizza", "locality": "Halifax", "openDuring": {"from": "2025-03-01T23:00:00Z"}, "pageToken": %q}`, pageToken)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/textsearch", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data SearchResult `json:"data"`
+		}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp.Data
+	}
+
+	first := search("")
+	assert.Len(t, first.Places, int(resultCount))
+	assert.NotEmpty(t, first.NextPageToken)
+	second := search(first.NextPageToken)
+	assert.Equal(t, []string{"p10", "p11"}, resultIDs(second.Places))
+	assert.Empty(t, second.NextPageToken)
+	assert.Equal(t, []string{"", ""}, tokens) // the first page is fetched again to resume within it
+}
+
+// Testing a nearby search radius beyond what Google accepts is refused, streamed or not
+func Test_Server_NearbyRadius(t *testing.T) {
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Google should not be called")
+	})
+	for _, url := range []string{"/nearbysearch", "/nearbysearch?stream=true"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"latitude": 44.6, "longitude": -63.5, "radius": 80000}`)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Body.String(), "radius must be at most 50000 meters")
+	}
+}
+
+// Testing geocode, reverse geocode and opening hours results are exported in the negotiated format
+func Test_Server_GeocodeExport(t *testing.T) {
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/maps/api/geocode/json" {
+			fmt.Fprint(w, `{"status": "OK", "results": [{"place_id": "halifax", "formatted_address": "Halifax, NS, Canada",
+				"geometry": {"location": {"lat": 44.65, "lng": -63.58}}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"id": "cafe", "regularOpeningHours": {"periods": [{"open": {"day": 0}}]}}`)
+	})
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	for _, url := range []string{"/geocode?address=Halifax&format=csv&columns=id,address,latitude,longitude", "/geodecode?latitude=44.65&longitude=-63.58&format=csv&columns=id,address,latitude,longitude"} {
+		w := get(url)
+		assert.Equal(t, http.StatusOK, w.Code, url)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "id,address,latitude,longitude\nhalifax,\"Halifax, NS, Canada\",44.65,-63.58\n", w.Body.String(), url)
+	}
+	w := get("/geocode?address=8FVC2222%2B22&format=geojson")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"type":"FeatureCollection"`)
+	w = get("/isopen/cafe?format=csv&columns=id,openDuring")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,openDuring\ncafe,OPEN\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/geocode:batch?format=csv&columns=id", strings.NewReader(`{"addresses": ["Halifax", " "]}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id\nhalifax\n", w.Body.String())
+
+	for _, url := range []string{"/geocode?address=Halifax&format=xml", "/geodecode?latitude=44.65&longitude=-63.58&format=xml", "/isopen/cafe?format=xml"} {
+		assert.Equal(t, http.StatusBadRequest, get(url).Code, url)
+	}
+}
+
+// Testing travel mode and routing preference are checked against the API's values before calling Google
+func Test_PlacesAlongRoute_Routing(t *testing.T) {
+	routing, err := PlacesAlongRoute{Lat: 44.6, Long: -63.5, TravelMode: "TWO_WHEELER", RoutingPreference: "TRAFFIC_AWARE"}.routing()
+	assert.NoError(t, err)
+	assert.Equal(t, geo.RoutingParameters{Origin: &geo.Location{Latitude: 44.6, Longitude: -63.5}, TravelMode: geo.TravelModeTwoWheeler, RoutingPreference: geo.RoutingPreferenceTrafficAware}, routing)
+	routing, err = PlacesAlongRoute{}.routing()
+	assert.NoError(t, err)
+	assert.Equal(t, geo.RoutingParameters{TravelMode: geo.TravelModeDrive}, routing)
+
+	for _, p := range []PlacesAlongRoute{
+		{TravelMode: "CAR"},
+		{RoutingPreference: "FASTEST"},
+		{TravelMode: "WALK", RoutingPreference: "TRAFFIC_AWARE"},
+	} {
+		_, err := p.routing()
+		assert.Error(t, err, p)
+	}
+}
+
+// Testing a route search sends the polyline and routing parameters and maps each routing summary to its place
+func Test_Server_SearchAlongRoute(t *testing.T) {
+	var req geo.TextSearchRequest
+	var fieldMask string
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/places:searchText", r.URL.Path)
+		fieldMask = r.Header.Get("X-Goog-FieldMask")
+		req = geo.TextSearchRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		fmt.Fprint(w, `{
+			"places": [{"id": "a"}, {"id": "b"}],
+			"routingSummaries": [
+				{"legs": [{"duration": "60s", "distanceMeters": 500}, {"duration": "120s", "distanceMeters": 900}]},
+				{"legs": [{"duration": "300s", "distanceMeters": 2500}, {"duration": "30s", "distanceMeters": 200}]}
+			]
+		}`)
+	})
+	search := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/searchalongroute", strings.NewReader(body)))
+		return w
+	}
+
+	w := search(`{"text": "coffee", "encodedPolyline": "_p~iF~ps|U", "latitude": 44.6, "longitude": -63.5, "travelMode": "TWO_WHEELER", "routingPreference": "TRAFFIC_AWARE"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "coffee", req.TextQuery)
+	assert.Equal(t, "_p~iF~ps|U", req.SearchAlongRouteParameters.Polyline.EncodedPolyline)
+	assert.Equal(t, geo.RoutingParameters{
+		Origin:            &geo.Location{Latitude: 44.6, Longitude: -63.5},
+		TravelMode:        geo.TravelModeTwoWheeler,
+		RoutingPreference: geo.RoutingPreferenceTrafficAware,
+	}, *req.RoutingParameters)
+	assert.Contains(t, fieldMask, "routingSummaries")
+
+	var resp struct {
+		Data geo.PlacesSearchResponse `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Data.Places, 2)
+	assert.Equal(t, "b", resp.Data.Places[1].Id)
+	assert.Len(t, resp.Data.RoutingSummaries, 2)
+	assert.Equal(t, int32(500), resp.Data.RoutingSummaries[0].Legs[0].DistanceMeters)
+	assert.Equal(t, "300s", resp.Data.RoutingSummaries[1].Legs[0].Duration)
+
+	search(`{"text": "coffee", "encodedPolyline": "_p~iF~ps|U"}`)
+	assert.Equal(t, geo.TravelModeDrive, req.RoutingParameters.TravelMode)
+	assert.Nil(t, req.RoutingParameters.Origin)
+
+	for _, body := range []string{
+		`{"text": "coffee", "encodedPolyline": "_p~iF~ps|U", "travelMode": "CAR"}`,
+		`{"text": "coffee", "encodedPolyline": "_p~iF~ps|U", "routingPreference": "FASTEST"}`,
+		`{"text": "coffee", "encodedPolyline": "_p~iF~ps|U", "travelMode": "WALK", "routingPreference": "TRAFFIC_AWARE"}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, search(body).Code, body)
+	}
+}
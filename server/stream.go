@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/geolocate/geo"
+)
+
+// Longest a streamed search may run. It replaces the server's write timeout, which is
+// sized for single page responses
+var streamTimeout = 2 * time.Minute
+
+// Most places a streamed text search follows page tokens for
+var streamMaxResults = 60
+
+// StreamSummary is the last line of a streamed search
+type StreamSummary struct {
+	Count    int                `json:"count"`
+	Complete bool               `json:"complete"` // False when the search stopped on an error. Places already sent are valid
+	Error    string             `json:"error,omitempty"`
+	Source   string             `json:"source,omitempty"`   // "local" when served from stored places because Google refused the search
+	Coverage *geo.CoverageStats `json:"coverage,omitempty"` // Nearby searches only
+}
+
+// A line of a streamed search. Every line but the last holds a place
+type streamRecord struct {
+	Place   *PlaceResult   `json:"place,omitempty"`
+	Summary *StreamSummary `json:"summary,omitempty"`
+}
+
+// Whether the client asked for results as newline delimited JSON, with ?stream=true or an Accept header
+func wantsStream(r *http.Request) bool {
+	return r.URL.Query().Get("stream") == "true" || strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// ndjsonStream writes places as they are found, flushing each line
+type ndjsonStream struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	enc    *json.Encoder
+	origin *geo.Location
+	window *OpenWindow
+	count  int
+	gone   bool // The client went away. Nothing more is written
+}
+
+func newNDJSONStream(w http.ResponseWriter, origin *geo.Location, window *OpenWindow) *ndjsonStream {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Now().Add(streamTimeout))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	return &ndjsonStream{w: w, rc: rc, enc: json.NewEncoder(w), origin: origin, window: window}
+}
+
+// Send a place, dropping it when it is closed during the open window. Returns false once the client is gone
+func (s *ndjsonStream) place(p geo.Place) bool {
+	if s.gone {
+		return false
+	}
+	if s.window != nil && !s.window.Annotate && geo.PlaceOpenDuring(p, s.window.From, s.window.To) != geo.OpenStatusOpen {
+		return true
+	}
+	result := newSearchResult([]geo.Place{p}, "", s.window, s.origin).Places[0]
+	if !s.write(streamRecord{Place: &result}) {
+		return false
+	}
+	s.count++
+	return true
+}
+
+// Finish the stream with its summary
+func (s *ndjsonStream) close(summary StreamSummary) {
+	summary.Count = s.count
+	s.write(streamRecord{Summary: &summary})
+}
+
+func (s *ndjsonStream) write(record streamRecord) bool {
+	if s.gone {
+		return false
+	}
+	if s.enc.Encode(record) != nil || s.rc.Flush() != nil {
+		s.gone = true
+	}
+	return !s.gone
+}
+
+// Stream every page of a text search. Stops when the client disconnects, as that cancels the request context
+func (s *Server) streamTextSearch(w http.ResponseWriter, r *http.Request, req geo.TextSearchRequest, header geo.PlacesHeader, params PlacesFromText) {
+	stream := newNDJSONStream(w, searchOrigin(params.Lat, params.Long), params.OpenDuring)
+	var summary StreamSummary
+	var searchErr error
+	for place, err := range s.client.TextSearchAll(r.Context(), &req, &header, streamMaxResults) {
+		if err != nil {
+			searchErr = err
+			break
+		}
+		s.savePlaces(place)
+		if !stream.place(place) {
+			return
+		}
+	}
+	if s.useLocalFallback(searchErr) && stream.count == 0 {
+		for _, place := range s.localText(params, false).Places {
+			stream.place(place)
+		}
+		summary.Source, searchErr = sourceLocal, nil
+	}
+	summary.Complete = searchErr == nil
+	if searchErr != nil {
+		summary.Error = searchErr.Error()
+	}
+	stream.close(summary)
+}
+
+// Stream a nearby search, splitting the circle into cells so more than one page of places is found
+func (s *Server) streamNearbySearch(w http.ResponseWriter, r *http.Request, req geo.NearbySearchRequest, header geo.PlacesHeader, params PlacesNearby) {
+	center := geo.Location{Latitude: params.Lat, Longitude: params.Long}
+	stream := newNDJSONStream(w, &center, params.OpenDuring)
+	opts := geo.CoverageOptions{OnPlace: func(place geo.Place) {
+		s.savePlaces(place)
+		stream.place(place)
+	}}
+	area := geo.Circle{Center: center, Radius: params.Radius}
+	resp, err := s.client.CoverageSearch(r.Context(), area, &req, &header, opts)
+	if stream.gone {
+		return
+	}
+	var summary StreamSummary
+	if s.useLocalFallback(err) && stream.count == 0 {
+		for _, place := range s.localNearby(params, req.IncludedTypes, 0).Places {
+			stream.place(place)
+		}
+		summary.Source, err = sourceLocal, nil
+	}
+	summary.Complete = err == nil
+	if err != nil {
+		summary.Error = err.Error()
+	} else if summary.Source == "" {
+		summary.Coverage = &resp.Stats
+	}
+	stream.close(summary)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// Lines of a streamed response
+func readStream(t *testing.T, body string) ([]PlaceResult, StreamSummary) {
+	var places []PlaceResult
+	var summary StreamSummary
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var record streamRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		switch {
+		case record.Place != nil:
+			places = append(places, *record.Place)
+		case record.Summary != nil:
+			summary = *record.Summary
+		}
+	}
+	return places, summary
+}
+
+// Testing text search places are streamed one per line, closed ones dropped, with a trailing summary
+func Test_StreamTextSearch(t *testing.T) {
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/places:searchText", r.URL.Path)
+		fmt.Fprint(w, `{"places": [
+			{"id": "open", "location": {"latitude": 44.65, "longitude": -63.57}, "regularOpeningHours": {"periods": [{"open": {"day": 0}}]}},
+			{"id": "closed", "location": {"latitude": 44.66, "longitude": -63.57}, "regularOpeningHours": {"periods": [{"open": {"day": 1, "hour": 9}, "close": {"day": 1, "hour": 10}}]}}
+		]}`)
+	})
+	params := PlacesFromText{Text: "pizza", Lat: 44.65, Long: -63.57, OpenDuring: &OpenWindow{From: time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)}}
+
+	w := httptest.NewRecorder()
+	s.streamTextSearch(w, httptest.NewRequest(http.MethodPost, "/textsearch?stream=true", nil), geo.TextSearchRequest{TextQuery: "pizza"}, geo.PlacesHeader{}, params)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	places, summary := readStream(t, w.Body.String())
+	assert.Len(t, places, 1)
+	assert.Equal(t, "open", places[0].Id)
+	assert.Equal(t, geo.OpenStatusOpen, places[0].OpenDuring)
+	assert.NotNil(t, places[0].DistanceMeters)
+	assert.Equal(t, StreamSummary{Count: 1, Complete: true}, summary)
+	assert.Equal(t, 2, s.store.Len())
+
+	// A disconnected client cancels the search
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	s.streamTextSearch(w, httptest.NewRequest(http.MethodPost, "/textsearch", nil).WithContext(ctx), geo.TextSearchRequest{TextQuery: "pizza"}, geo.PlacesHeader{}, PlacesFromText{Text: "pizza"})
+	places, summary = readStream(t, w.Body.String())
+	assert.Empty(t, places)
+	assert.False(t, summary.Complete)
+}
+
+// Testing nearby search cells are streamed, and stored places are streamed when Google refuses the search
+func Test_StreamNearbySearch(t *testing.T) {
+	quota := false
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		if quota {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"places": [{"id": "a", "types": ["cafe"], "location": {"latitude": 44.6489, "longitude": -63.5752}},
+			{"id": "far", "location": {"latitude": 45, "longitude": -63}}]}`)
+	})
+	params := PlacesNearby{Lat: 44.6488, Long: -63.5752, Radius: 500}
+	req := geo.NearbySearchRequest{MaxResultCount: 20, IncludedTypes: []geo.PlaceType{geo.Cafe}}
+
+	w := httptest.NewRecorder()
+	s.streamNearbySearch(w, httptest.NewRequest(http.MethodPost, "/nearbysearch", nil), req, geo.PlacesHeader{}, params)
+	places, summary := readStream(t, w.Body.String())
+	assert.Len(t, places, 1)
+	assert.True(t, summary.Complete)
+	assert.Equal(t, 1, summary.Coverage.Calls)
+
+	quota = true
+	w = httptest.NewRecorder()
+	s.streamNearbySearch(w, httptest.NewRequest(http.MethodPost, "/nearbysearch", nil), req, geo.PlacesHeader{}, params)
+	places, summary = readStream(t, w.Body.String())
+	assert.Len(t, places, 1)
+	assert.Equal(t, StreamSummary{Count: 1, Complete: true, Source: sourceLocal}, summary)
+}
+
+// Testing streaming is asked for with a query param or the Accept header
+func Test_WantsStream(t *testing.T) {
+	assert.True(t, wantsStream(httptest.NewRequest(http.MethodPost, "/textsearch?stream=true", nil)))
+	r := httptest.NewRequest(http.MethodPost, "/textsearch", nil)
+	assert.False(t, wantsStream(r))
+	r.Header.Set("Accept", "application/x-ndjson")
+	assert.True(t, wantsStream(r))
+}
+
+// Testing a stream asked for along with an export format is refused rather than sent as NDJSON
+func Test_Stream_Format(t *testing.T) {
+	s := newTestServer(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Google should not be called")
+	})
+	for _, url := range []string{"/textsearch?stream=true&format=csv", "/nearbysearch?stream=true&format=geojson"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"text": "pizza", "latitude": 44.6, "longitude": -63.5, "radius": 1000}`)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Body.String(), "format is not supported when streaming")
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/geolocate/store"
+	"github.com/geolocate/watch"
+	"github.com/gorilla/mux"
+)
+
+// Watched places are checked in the background and their changes posted to webhooks. Config.WatchDir keeps
+// watchlists and undelivered events on disk
+func (s *Server) newWatcher() *watch.Watcher {
+	w, err := watch.New(s.client, store.RealClock, watch.Options{Dir: s.config.WatchDir, Logger: s.logger})
+	if err != nil {
+		s.logger.Printf("watch: not started, %s", err)
+		return nil
+	}
+	return w
+}
+
+// Watchlist routes are admin only, as every watched place costs a details lookup each hour
+func (s *Server) watcherReady(w http.ResponseWriter, r *http.Request) bool {
+	if !s.isAdmin(r) {
+		responseJson(w, http.StatusForbidden, Response{Data: nil, Error: "Admin token missing or invalid"})
+		return false
+	}
+	if s.watcher == nil {
+		responseJson(w, http.StatusServiceUnavailable, Response{Data: nil, Error: "Watcher is not running, API_KEY is not set"})
+		return false
+	}
+	return true
+}
+
+// Secrets are only shown when a watchlist is created
+func withoutSecret(l watch.Watchlist) watch.Watchlist {
+	l.Secret = ""
+	return l
+}
+
+// Create a watchlist. Body: name, placeIds, fields (JSON names of Place fields), webhookUrl and an optional secret.
+// The response holds the secret webhooks are signed with
+func (s *Server) CreateWatchlist(w http.ResponseWriter, r *http.Request) {
+	if !s.watcherReady(w, r) {
+		return
+	}
+	var list watch.Watchlist
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	list, err := s.watcher.Add(list)
+	if err != nil {
+		responseJson(w, http.StatusBadRequest, Response{Data: nil, Error: err.Error()})
+		return
+	}
+	responseJson(w, http.StatusCreated, Response{Data: list, Error: ""})
+}
+
+// List watchlists
+func (s *Server) GetWatchlists(w http.ResponseWriter, r *http.Request) {
+	if !s.watcherReady(w, r) {
+		return
+	}
+	lists := s.watcher.List()
+	for i := range lists {
+		lists[i] = withoutSecret(lists[i])
+	}
+	responseJson(w, http.StatusOK, Response{Data: lists, Error: ""})
+}
+
+// Look up a watchlist by watchlistID
+func (s *Server) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	if !s.watcherReady(w, r) {
+		return
+	}
+	list, ok := s.watcher.Get(mux.Vars(r)["watchlistID"])
+	if !ok {
+		responseJson(w, http.StatusNotFound, Response{Data: nil, Error: "Watchlist not found"})
+		return
+	}
+	responseJson(w, http.StatusOK, Response{Data: withoutSecret(list), Error: ""})
+}
+
+// Delete a watchlist by watchlistID
+func (s *Server) DeleteWatchlist(w http.ResponseWriter, r *http.Request) {
+	if !s.watcherReady(w, r) {
+		return
+	}
+	err := s.watcher.Remove(mux.Vars(r)["watchlistID"])
+	switch {
+	case errors.Is(err, watch.ErrNotFound):
+		responseJson(w, http.StatusNotFound, Response{Data: nil, Error: "Watchlist not found"})
+	case err != nil:
+		responseJson(w, http.StatusInternalServerError, Response{Data: nil, Error: err.Error()})
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// List the most recent webhook events that could not be delivered
+func (s *Server) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !s.watcherReady(w, r) {
+		return
+	}
+	responseJson(w, http.StatusOK, Response{Data: s.watcher.DeadLetters(), Error: ""})
+}
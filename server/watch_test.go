@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/watch"
+	"github.com/stretchr/testify/assert"
+)
+
+type noDetails struct{}
+
+func (noDetails) PlaceDetails(ctx context.Context, id string, h *geo.PlacesHeader) (geo.Place, error) {
+	return geo.Place{Id: id}, nil
+}
+
+// Testing watchlists are created with their secret, listed without it and deleted, all behind the admin token
+func Test_Watchlists(t *testing.T) {
+	s := newTestServer(t, Config{AdminToken: "secret"}, nil)
+	s.watcher, _ = watch.New(noDetails{}, nil, watch.Options{})
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.Header.Set("X-Admin-Token", "secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watchlists", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/watchlists", `{"placeIds":["a"],"webhookUrl":"not a url"}`).Code)
+
+	w = do(http.MethodPost, "/watchlists", `{"name":"bars","placeIds":["a"],"webhookUrl":"https://example.com/hook"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data watch.Watchlist `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.NotEmpty(t, created.Data.Secret)
+
+	w = do(http.MethodGet, "/watchlists/"+created.Data.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Data.Secret)
+	w = do(http.MethodGet, "/watchlists", "")
+	assert.Contains(t, w.Body.String(), created.Data.ID)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/watchlists/"+created.Data.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/watchlists/"+created.Data.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/watchlists/"+created.Data.ID, "").Code)
+}
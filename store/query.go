@@ -0,0 +1,141 @@
+package store
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/geo/geometry"
+)
+
+// Query selects stored places. Zero fields match everything
+type Query struct {
+	City   string        // Locality, matched ignoring case. Eg: Halifax
+	Types  []string      // Places with any of these types or primary types
+	Near   *geo.Location // With Radius, places within Radius meters of Near, nearest first
+	Radius float64
+	OpenAt time.Time // Places open at this time. Places without opening hours are left out
+	Limit  int       // At most this many places. 0 is no limit
+}
+
+// Result is a place found by a query
+type Result struct {
+	Record
+	DistanceMeters *float64 `json:"distanceMeters,omitempty"` // From Query.Near
+	Score          float64  `json:"score,omitempty"`          // Relevance to SearchQuery.Text
+}
+
+// Find returns the places matching q. Results are ordered by distance when Near is set, otherwise by name.
+// Places past MaxAge are left out
+func (s *Store) Find(q Query) []Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	var results []Result
+	for id := range s.candidates(q) {
+		r := s.records[id]
+		if r.expired(now) || !q.matches(r) {
+			continue
+		}
+		result := Result{Record: r}
+		if q.Near != nil {
+			d := geometry.Haversine(q.Near.Point(), r.Location.Point())
+			if d > q.Radius {
+				continue
+			}
+			result.DistanceMeters = &d
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.DistanceMeters != nil && *a.DistanceMeters != *b.DistanceMeters {
+			return *a.DistanceMeters < *b.DistanceMeters
+		}
+		if a.DisplayName.Text != b.DisplayName.Text {
+			return a.DisplayName.Text < b.DisplayName.Text
+		}
+		return a.Id < b.Id
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+// IDs worth checking, taken from the most selective index the query can use
+func (s *Store) candidates(q Query) map[string]bool {
+	var sets []map[string]bool
+	if q.City != "" {
+		sets = append(sets, s.byCity[normalize(q.City)])
+	}
+	if len(q.Types) > 0 {
+		types := map[string]bool{}
+		for _, t := range q.Types {
+			for id := range s.byType[t] {
+				types[id] = true
+			}
+		}
+		sets = append(sets, types)
+	}
+	if q.Near != nil {
+		sets = append(sets, s.nearby(*q.Near, q.Radius))
+	}
+	if len(sets) == 0 {
+		all := make(map[string]bool, len(s.records))
+		for id := range s.records {
+			all[id] = true
+		}
+		return all
+	}
+	return slices.MinFunc(sets, func(a, b map[string]bool) int { return len(a) - len(b) })
+}
+
+// IDs in the geohash cells covering the circle. Coarser cells are used for large circles,
+// whose index keys are prefixes of the stored cells
+func (s *Store) nearby(center geo.Location, radius float64) map[string]bool {
+	circle := geo.Circle{Center: center, Radius: int64(math.Ceil(radius))}
+	ids := map[string]bool{}
+	for precision := cellPrecision; precision >= 1; precision-- {
+		cells, err := geo.GeohashCovering(circle, precision)
+		if err != nil {
+			continue
+		}
+		for _, cell := range cells {
+			if precision == cellPrecision {
+				for id := range s.byCell[cell] {
+					ids[id] = true
+				}
+				continue
+			}
+			for key, cellIDs := range s.byCell {
+				if strings.HasPrefix(key, cell) {
+					for id := range cellIDs {
+						ids[id] = true
+					}
+				}
+			}
+		}
+		return ids
+	}
+	return ids
+}
+
+func (q Query) matches(r Record) bool {
+	if q.City != "" && normalize(r.City) != normalize(q.City) {
+		return false
+	}
+	if len(q.Types) > 0 && !slices.ContainsFunc(recordTypes(r), func(t string) bool { return slices.Contains(q.Types, t) }) {
+		return false
+	}
+	if q.Near != nil && r.Geohash == "" {
+		return false
+	}
+	if !q.OpenAt.IsZero() && geo.PlaceOpenDuring(r.Place, q.OpenAt, time.Time{}) != geo.OpenStatusOpen {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/geolocate/client"
+	"github.com/geolocate/geo"
+)
+
+// Google's terms allow place content to be kept for 30 days. Only the place ID may be kept longer
+const MaxAge = 30 * 24 * time.Hour
+
+// Clock lets tests drive the refresher without waiting
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock is the wall clock
+var RealClock Clock = realClock{}
+
+// DetailsFetcher looks up a place. geo.GeoClient implements it
+type DetailsFetcher interface {
+	PlaceDetails(ctx context.Context, id string, h *geo.PlacesHeader) (geo.Place, error)
+}
+
+// Fields requested when refreshing a place. Everything the store indexes and the server returns
+var RefreshFieldMasks = []geo.PlaceFieldMask{
+	geo.PlaceFieldMaskPlaceID, geo.PlaceFieldMaskDispName, geo.PlaceFieldMaskTypes, geo.PlaceFieldMaskPrimaryType,
+	geo.PlaceFieldMaskFormattedAddress, geo.PlaceFieldMaskAddressComponents, geo.PlaceFieldMaskLocation,
+	geo.PlaceFieldMaskBusinessStatus, geo.PlaceFieldMaskRatings, geo.PlaceFieldMaskUserRatingCount,
+	geo.PlaceFieldMaskOpeningHours, geo.PlaceFieldMaskCurrentOpeningHours, geo.PlaceFieldMaskTimezone, geo.PlaceFieldMaskUtcOffset,
+}
+
+// RefreshOptions tune the refresher. Zero values take the defaults
+type RefreshOptions struct {
+	RefreshAfter      time.Duration // Places older than this are refreshed. Defaults to 25 days
+	Interval          time.Duration // Time between scans. Defaults to an hour
+	BatchSize         int           // Places refreshed per scan, oldest first. Defaults to 500
+	RequestsPerSecond float64       // Details calls budget, separate from the client's limit so refreshes never starve user requests. Defaults to 1
+	Logger            *log.Logger   // Reports failed scans. Defaults to the standard logger
+}
+
+// RefreshStats counts what the refresher did since it started
+type RefreshStats struct {
+	Scans     int       `json:"scans"`
+	Checked   int       `json:"checked"`
+	Refreshed int       `json:"refreshed"`
+	Moved     int       `json:"moved"`   // Places Google now returns under a new ID
+	Deleted   int       `json:"deleted"` // Places Google no longer knows, or that could not be refreshed within MaxAge
+	Failed    int       `json:"failed"`  // Refreshes that failed and will be retried
+	LastScan  time.Time `json:"lastScan,omitempty"`
+	Pending   int       `json:"pending"` // Places due for refresh after the last scan
+}
+
+// Refresher keeps stored places within the caching deadline by re-fetching their details
+type Refresher struct {
+	store   *Store
+	fetcher DetailsFetcher
+	clock   Clock
+	opts    RefreshOptions
+
+	mu    sync.Mutex
+	stats RefreshStats
+}
+
+func NewRefresher(s *Store, fetcher DetailsFetcher, clock Clock, opts RefreshOptions) *Refresher {
+	if opts.RefreshAfter <= 0 {
+		opts.RefreshAfter = 25 * 24 * time.Hour
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.RequestsPerSecond <= 0 {
+		opts.RequestsPerSecond = 1
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	if clock == nil {
+		clock = RealClock
+	}
+	return &Refresher{store: s, fetcher: fetcher, clock: clock, opts: opts}
+}
+
+// Stats returns the progress so far
+func (r *Refresher) Stats() RefreshStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Run scans every Interval until ctx is done
+func (r *Refresher) Run(ctx context.Context) {
+	for {
+		if err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			r.opts.Logger.Printf("refresher: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.clock.After(r.opts.Interval):
+		}
+	}
+}
+
+// RunOnce deletes every place past MaxAge, then refreshes the oldest places due, up to BatchSize,
+// pacing calls to the rate budget
+func (r *Refresher) RunOnce(ctx context.Context) error {
+	now := r.clock.Now()
+	due := r.store.UpdatedBefore(now.Add(-min(r.opts.RefreshAfter, MaxAge)), 0)
+	// Oldest first, so the expired places lead. They go whatever the batch size, or a long queue would keep them stored
+	expired := 0
+	for expired < len(due) && due[expired].expired(now) {
+		if err := r.store.Delete(due[expired].Id); err != nil {
+			return err
+		}
+		expired++
+	}
+	due = due[expired:]
+	batch := due[:min(len(due), r.opts.BatchSize)]
+	pause := time.Duration(float64(time.Second) / r.opts.RequestsPerSecond)
+	r.update(func(s *RefreshStats) {
+		s.Scans++
+		s.LastScan = now
+		s.Deleted += expired
+		s.Pending = len(due)
+	})
+	for i, record := range batch {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-r.clock.After(pause):
+			}
+		}
+		if err := r.refresh(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Refresh one place. Only store errors are returned; upstream errors are counted and retried next scan
+func (r *Refresher) refresh(ctx context.Context, record Record) error {
+	header := geo.PlacesHeader{FieldMasks: RefreshFieldMasks}
+	place, err := r.fetcher.PlaceDetails(ctx, record.Id, &header)
+	var httpErr client.HttpError
+	switch {
+	case errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound:
+		err = r.store.Delete(record.Id)
+		r.update(func(s *RefreshStats) { s.Checked++; s.Deleted++; s.Pending-- })
+	case err != nil:
+		// Content past the deadline must go even if Google could not be reached
+		if record.expired(r.clock.Now()) {
+			r.update(func(s *RefreshStats) { s.Checked++; s.Deleted++; s.Pending-- })
+			return r.store.Delete(record.Id)
+		}
+		r.update(func(s *RefreshStats) { s.Checked++; s.Failed++ })
+		return nil
+	case place.Id != "" && place.Id != record.Id:
+		// Google refreshed the place ID. Keep the place under its new ID only
+		if err = r.store.Delete(record.Id); err == nil {
+			err = r.store.Replace(place)
+		}
+		r.update(func(s *RefreshStats) { s.Checked++; s.Moved++; s.Pending-- })
+	default:
+		place.Id = record.Id
+		err = r.store.Replace(place)
+		r.update(func(s *RefreshStats) { s.Checked++; s.Refreshed++; s.Pending-- })
+	}
+	return err
+}
+
+func (r *Refresher) update(f func(*RefreshStats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(&r.stats)
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing places nearing the deadline are refreshed, moved or deleted, paced by the rate budget
+func Test_Refresher(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	s, _ := Open("")
+	s.now = clock.Now
+	assert.NoError(t, s.Upsert(geo.Place{Id: "fresh", Rating: 4}))
+	clock.Advance(-26 * 24 * time.Hour)
+	assert.NoError(t, s.Upsert(
+		geo.Place{Id: "same", Rating: 3, Photos: []geo.Photo{{}}},
+		geo.Place{Id: "old-id", Rating: 2},
+		geo.Place{Id: "closed", Rating: 1},
+	))
+	clock.Advance(26 * 24 * time.Hour)
+
+	fetcher := &testutil.Fetcher{Places: map[string]geo.Place{
+		"same":   {Id: "same", Rating: 3.5},
+		"old-id": {Id: "new-id", Rating: 2.5},
+	}}
+	r := NewRefresher(s, fetcher, clock, RefreshOptions{RequestsPerSecond: 2})
+	assert.NoError(t, r.RunOnce(context.Background()))
+	assert.Equal(t, []string{"closed", "old-id", "same"}, fetcher.Calls)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, clock.Waits())
+
+	same, _ := s.Get("same")
+	assert.Equal(t, 3.5, same.Rating)
+	assert.Empty(t, same.Photos) // replaced, not merged
+	assert.Equal(t, clock.Now(), same.UpdatedAt)
+	_, ok := s.Get("old-id")
+	assert.False(t, ok)
+	moved, ok := s.Get("new-id")
+	assert.True(t, ok)
+	assert.Equal(t, 2.5, moved.Rating)
+	_, ok = s.Get("closed")
+	assert.False(t, ok)
+	_, ok = s.Get("fresh")
+	assert.True(t, ok)
+
+	stats := r.Stats()
+	assert.Equal(t, RefreshStats{Scans: 1, Checked: 3, Refreshed: 1, Moved: 1, Deleted: 1, LastScan: stats.LastScan}, stats)
+}
+
+// Testing upstream failures are retried until the 30 day deadline, then the place is deleted
+func Test_Refresher_Failures(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	s, _ := Open("")
+	s.now = clock.Now
+	assert.NoError(t, s.Upsert(geo.Place{Id: "a"}, geo.Place{Id: "b"}))
+	clock.Advance(26 * 24 * time.Hour)
+
+	fetcher := &testutil.Fetcher{Err: errors.New("quota exceeded")}
+	r := NewRefresher(s, fetcher, clock, RefreshOptions{BatchSize: 1, Interval: 24 * time.Hour})
+	assert.NoError(t, r.RunOnce(context.Background()))
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, 1, r.Stats().Failed)
+	assert.Equal(t, 2, r.Stats().Pending)
+
+	clock.Advance(5 * 24 * time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for s.Len() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	r.Run(ctx) // one place per scan, a day apart
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, 2, r.Stats().Deleted)
+}
+
+// Testing a search hit carrying fewer fields than an earlier details lookup does not postpone the refresh
+func Test_Refresher_PartialUpsert(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	clock := testutil.NewClock(t0)
+	s, _ := Open("")
+	s.now = clock.Now
+	assert.NoError(t, s.Upsert(geo.Place{Id: "cafe", Rating: 4, PhoneNumber: "902-555-0100"}))
+	clock.Advance(29 * 24 * time.Hour)
+	assert.NoError(t, s.Upsert(geo.Place{Id: "cafe", Rating: 4.2}))
+	record, _ := s.Get("cafe")
+	assert.Equal(t, 4.2, record.Rating)
+	assert.Equal(t, t0, record.UpdatedAt)
+
+	clock.Advance(24 * time.Hour) // t0 + 30 days
+	assert.Len(t, s.UpdatedBefore(clock.Now().Add(-25*24*time.Hour), 0), 1)
+	r := NewRefresher(s, &testutil.Fetcher{Err: errors.New("unavailable")}, clock, RefreshOptions{})
+	assert.NoError(t, r.RunOnce(context.Background()))
+	_, ok := s.Get("cafe")
+	assert.False(t, ok) // past MaxAge and could not be refreshed
+	assert.Equal(t, 1, r.Stats().Deleted)
+
+	// Once every stored field comes back, the record is fresh again
+	assert.NoError(t, s.Upsert(geo.Place{Id: "pizza", Rating: 4}))
+	clock.Advance(time.Hour)
+	assert.NoError(t, s.Upsert(geo.Place{Id: "pizza", Rating: 4.5}))
+	record, _ = s.Get("pizza")
+	assert.Equal(t, clock.Now(), record.UpdatedAt)
+}
+
+// Testing every place past MaxAge is deleted in one scan, not only those within the batch
+func Test_Refresher_Expired(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	s, _ := Open("")
+	s.now = clock.Now
+	assert.NoError(t, s.Upsert(geo.Place{Id: "a"}, geo.Place{Id: "b"}, geo.Place{Id: "c"}))
+	clock.Advance(4 * 24 * time.Hour)
+	assert.NoError(t, s.Upsert(geo.Place{Id: "due"}))
+	clock.Advance(27 * 24 * time.Hour)
+
+	fetcher := &testutil.Fetcher{Places: map[string]geo.Place{"due": {Id: "due"}}}
+	r := NewRefresher(s, fetcher, clock, RefreshOptions{BatchSize: 1})
+	assert.NoError(t, r.RunOnce(context.Background()))
+	assert.Equal(t, []string{"due"}, fetcher.Calls)
+	assert.Equal(t, 1, s.Len())
+	assert.Equal(t, 3, r.Stats().Deleted)
+	assert.Equal(t, 1, r.Stats().Refreshed)
+}
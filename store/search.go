@@ -0,0 +1,143 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/geo/geometry"
+)
+
+// BM25 parameters. k1 caps how much a repeated term counts, b how much long documents are penalised
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Names are what people search for, so their terms count this many times over types and addresses
+const nameWeight = 3
+
+// Words too common in searches and addresses to rank by. Eg: "pizza in halifax"
+var stopWords = map[string]bool{"a": true, "an": true, "and": true, "at": true, "in": true, "near": true, "of": true, "on": true, "the": true}
+
+// textIndex is an inverted index of the terms in stored places' names, types and addresses
+type textIndex struct {
+	postings map[string]map[string]int // term to place ID to weighted term frequency
+	lengths  map[string]int            // weighted terms per place
+	total    int
+}
+
+func newTextIndex() textIndex {
+	return textIndex{postings: map[string]map[string]int{}, lengths: map[string]int{}}
+}
+
+func (ix *textIndex) add(r Record) {
+	terms := recordTerms(r)
+	for term, n := range terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[string]int{}
+		}
+		ix.postings[term][r.Id] = n
+		ix.lengths[r.Id] += n
+	}
+	ix.total += ix.lengths[r.Id]
+}
+
+func (ix *textIndex) remove(r Record) {
+	for term := range recordTerms(r) {
+		delete(ix.postings[term], r.Id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.total -= ix.lengths[r.Id]
+	delete(ix.lengths, r.Id)
+}
+
+// Weighted term frequencies of a record. Types are split on underscores so pizza_restaurant matches pizza
+func recordTerms(r Record) map[string]int {
+	terms := map[string]int{}
+	for _, t := range tokenize(r.DisplayName.Text) {
+		terms[t] += nameWeight
+	}
+	for _, typ := range recordTypes(r) {
+		for _, t := range tokenize(strings.ReplaceAll(typ, "_", " ")) {
+			terms[t]++
+		}
+	}
+	for _, t := range tokenize(r.FormattedAddress) {
+		terms[t]++
+	}
+	return terms
+}
+
+// Lower case words and numbers in s, without stop words
+func tokenize(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+	tokens := words[:0]
+	for _, w := range words {
+		if !stopWords[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// SearchQuery ranks stored places against free text, like a text search does
+type SearchQuery struct {
+	Text   string
+	Near   *geo.Location // Biases results towards Near. Unlike Query it does not exclude far away places
+	Radius float64       // Distance in meters at which a match scores half as much as one at Near. Defaults to 5 km
+	Limit  int           // At most this many places. 0 is no limit
+}
+
+// Search returns places matching any term of q.Text, best first. Matches are scored with BM25 and,
+// when Near is set, divided by 1 + distance/Radius so nearby places rank higher. Places past MaxAge are left out
+func (s *Store) Search(q SearchQuery) []Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scale := q.Radius
+	if scale <= 0 {
+		scale = 5000
+	}
+	scores := map[string]float64{}
+	n := float64(len(s.text.lengths))
+	avgLen := float64(s.text.total) / math.Max(n, 1)
+	for _, term := range tokenize(q.Text) {
+		postings := s.text.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			f := float64(tf)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(s.text.lengths[id])/avgLen)
+			scores[id] += idf * f * (bm25K1 + 1) / (f + norm)
+		}
+	}
+	now := s.now()
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		result := Result{Record: s.records[id]}
+		if result.expired(now) {
+			continue
+		}
+		if q.Near != nil && result.Geohash != "" {
+			d := geometry.Haversine(q.Near.Point(), result.Location.Point())
+			result.DistanceMeters = &d
+			score /= 1 + d/scale
+		}
+		result.Score = score
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Id < results[j].Id
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
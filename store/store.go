@@ -0,0 +1,353 @@
+// Package store keeps every place the server has seen in an embedded, file backed database so
+// they can be browsed, or searched when Google cannot be reached. Places are indexed by ID, city, type,
+// geohash cell and the words in their names, types and addresses.
+//
+// The database is an append only log of JSON lines replayed into memory when it is opened, so there
+// is no external service to run. The log is compacted when it holds mostly superseded lines.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geolocate/geo"
+)
+
+// Precision of the geohash cells places are indexed by, about 1.2 x 0.6 km
+const cellPrecision = 6
+
+// Record is a stored place with the fields it is indexed by
+type Record struct {
+	geo.Place
+	City      string    `json:"city,omitempty"` // Locality from the place's address components
+	Geohash   string    `json:"geohash,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"` // When the oldest of the place's fields was fetched from Google
+}
+
+// A line in the log
+type logEntry struct {
+	Op     string  `json:"op"` // put or delete
+	Record *Record `json:"record,omitempty"`
+	ID     string  `json:"id,omitempty"`
+}
+
+// Store is safe for concurrent use
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	log     *os.File
+	lines   int // lines in the log, live or superseded
+	records map[string]Record
+	byCity  index
+	byType  index
+	byCell  index
+	text    textIndex
+	now     func() time.Time
+}
+
+// index maps a key to the IDs of the places with it
+type index map[string]map[string]bool
+
+func (ix index) add(key, id string) {
+	if key == "" {
+		return
+	}
+	if ix[key] == nil {
+		ix[key] = map[string]bool{}
+	}
+	ix[key][id] = true
+}
+
+func (ix index) remove(key, id string) {
+	delete(ix[key], id)
+	if len(ix[key]) == 0 {
+		delete(ix, key)
+	}
+}
+
+// Open loads the store at path, creating it if needed. An empty path keeps places in memory only
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, records: map[string]Record{}, byCity: index{}, byType: index{}, byCell: index{}, text: newTextIndex(), now: time.Now}
+	if path == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // a line cut short by a crash
+		}
+		s.lines++
+		switch {
+		case entry.Op == "put" && entry.Record != nil:
+			s.index(*entry.Record)
+		case entry.Op == "delete":
+			s.unindex(entry.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.log = f
+	if s.needsCompaction() {
+		if err := s.compact(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close closes the log file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return nil
+	}
+	err := s.log.Close()
+	s.log = nil
+	return err
+}
+
+// Upsert stores places. Fields missing from a place, because its field mask left them out,
+// keep their stored values, and then the record keeps its UpdatedAt so the older fields are still
+// refreshed in time. Places without an ID are skipped
+func (s *Store) Upsert(places ...geo.Place) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, p := range places {
+		if p.Id == "" {
+			continue
+		}
+		updatedAt := now
+		if old, ok := s.records[p.Id]; ok {
+			var kept bool
+			if p, kept = mergePlace(old.Place, p); kept {
+				updatedAt = old.UpdatedAt
+			}
+		}
+		r := newRecord(p, updatedAt)
+		if err := s.append(logEntry{Op: "put", Record: &r}); err != nil {
+			return err
+		}
+		s.index(r)
+	}
+	if s.needsCompaction() {
+		return s.compact()
+	}
+	return nil
+}
+
+// Replace stores p as is, dropping any fields stored for it before. Used when a place is refreshed
+// with a full field mask, so stale content does not linger
+func (s *Store) Replace(p geo.Place) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.Id == "" {
+		return errors.New("store: place has no ID")
+	}
+	r := newRecord(p, s.now())
+	if err := s.append(logEntry{Op: "put", Record: &r}); err != nil {
+		return err
+	}
+	s.index(r)
+	return nil
+}
+
+// UpdatedBefore returns up to limit places last updated before t, oldest first then by ID. A limit of 0 returns all of them
+func (s *Store) UpdatedBefore(t time.Time, limit int) []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var due []Record
+	for _, r := range s.records {
+		if r.UpdatedAt.Before(t) {
+			due = append(due, r)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].UpdatedAt.Equal(due[j].UpdatedAt) {
+			return due[i].UpdatedAt.Before(due[j].UpdatedAt)
+		}
+		return due[i].Id < due[j].Id
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
+
+// Get returns the stored place with id. Places past MaxAge are not returned
+func (s *Store) Get(id string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.records[id]
+	if !ok || r.expired(s.now()) {
+		return Record{}, false
+	}
+	return r, true
+}
+
+// Delete removes a place. Deleting a missing place is not an error
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[id]; !ok {
+		return nil
+	}
+	if err := s.append(logEntry{Op: "delete", ID: id}); err != nil {
+		return err
+	}
+	s.unindex(id)
+	return nil
+}
+
+// Len returns the number of places stored
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Whether the record's content is past MaxAge at now. Expired records stay stored until the refresher
+// deletes them, but are left out of lookups so the limit holds without a refresher
+func (r Record) expired(now time.Time) bool {
+	return now.Sub(r.UpdatedAt) >= MaxAge
+}
+
+func newRecord(p geo.Place, now time.Time) Record {
+	r := Record{Place: p, City: p.Address().Locality, UpdatedAt: now}
+	if p.Location != (geo.Location{}) {
+		r.Geohash = geo.EncodeGeohash(p.Location.Latitude, p.Location.Longitude, cellPrecision)
+	}
+	return r
+}
+
+// Copy the fields set in p over old. Field masks mean a search may return fewer fields than a
+// previous details lookup, and those should not be wiped. Reports whether any of old's fields were kept
+func mergePlace(old, p geo.Place) (geo.Place, bool) {
+	dst, src := reflect.ValueOf(&old).Elem(), reflect.ValueOf(p)
+	kept := false
+	for i := 0; i < src.NumField(); i++ {
+		switch {
+		case !src.Field(i).IsZero():
+			dst.Field(i).Set(src.Field(i))
+		case !dst.Field(i).IsZero():
+			kept = true
+		}
+	}
+	return old, kept
+}
+
+func (s *Store) index(r Record) {
+	s.unindex(r.Id)
+	s.records[r.Id] = r
+	s.byCity.add(normalize(r.City), r.Id)
+	s.byCell.add(r.Geohash, r.Id)
+	for _, t := range recordTypes(r) {
+		s.byType.add(t, r.Id)
+	}
+	s.text.add(r)
+}
+
+func (s *Store) unindex(id string) {
+	r, ok := s.records[id]
+	if !ok {
+		return
+	}
+	delete(s.records, id)
+	s.byCity.remove(normalize(r.City), id)
+	s.byCell.remove(r.Geohash, id)
+	for _, t := range recordTypes(r) {
+		s.byType.remove(t, id)
+	}
+	s.text.remove(r)
+}
+
+// Types a record is indexed under. The primary type is usually one of Types but not always
+func recordTypes(r Record) []string {
+	types := append([]string{}, r.Types...)
+	if r.PrimaryType != "" {
+		types = append(types, r.PrimaryType)
+	}
+	return types
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func (s *Store) append(entry logEntry) error {
+	if s.path == "" {
+		return nil
+	}
+	if s.log == nil {
+		return errors.New("store: closed")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := s.log.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.lines++
+	return nil
+}
+
+// Compact once superseded lines outnumber live places and the log is worth rewriting
+func (s *Store) needsCompaction() bool {
+	return s.log != nil && s.lines > 1000 && s.lines > 2*len(s.records)
+}
+
+// Rewrite the log with one line per place, swapping it in with a rename so a crash leaves either log intact
+func (s *Store) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, r := range s.records {
+		if err := enc.Encode(logEntry{Op: "put", Record: &r}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.log.Close()
+	s.log, s.lines = f, len(s.records)
+	return nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func halifaxComponents() []geo.PlaceAddressComponent {
+	return []geo.PlaceAddressComponent{
+		{LongText: "Halifax", ShortText: "Halifax", Types: []string{"locality", "political"}},
+		{LongText: "Canada", ShortText: "CA", Types: []string{"country", "political"}},
+	}
+}
+
+func testPlaces() []geo.Place {
+	allDay := geo.OpeningHours{Periods: []geo.Period{{Open: geo.Point{Day: 0}}}}
+	return []geo.Place{
+		{Id: "pizza", DisplayName: geo.LocalizedText{Text: "Pizza Corner"}, PrimaryType: "pizza_restaurant", Types: []string{"pizza_restaurant", "restaurant"},
+			Location: geo.Location{Latitude: 44.6450, Longitude: -63.5730}, AddressComponents: halifaxComponents(), RegularOpeningHours: allDay},
+		{Id: "cafe", DisplayName: geo.LocalizedText{Text: "Harbour Cafe"}, PrimaryType: "cafe", Types: []string{"cafe"},
+			Location: geo.Location{Latitude: 44.6480, Longitude: -63.5700}, AddressComponents: halifaxComponents()},
+		{Id: "diner", DisplayName: geo.LocalizedText{Text: "Dartmouth Diner"}, Types: []string{"restaurant"},
+			Location: geo.Location{Latitude: 44.6700, Longitude: -63.5600}},
+		{Id: "boston", DisplayName: geo.LocalizedText{Text: "Boston Pizza"}, PrimaryType: "pizza_restaurant",
+			Location: geo.Location{Latitude: 42.3601, Longitude: -71.0589}},
+	}
+}
+
+func ids(results []Result) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Id)
+	}
+	return ids
+}
+
+// Testing places are found by city, type, distance and opening hours
+func Test_Find(t *testing.T) {
+	s, err := Open("")
+	assert.NoError(t, err)
+	assert.NoError(t, s.Upsert(testPlaces()...))
+	assert.Equal(t, 4, s.Len())
+
+	assert.Equal(t, []string{"boston", "diner", "cafe", "pizza"}, ids(s.Find(Query{})))
+	assert.Equal(t, []string{"cafe", "pizza"}, ids(s.Find(Query{City: "halifax"})))
+	assert.Equal(t, []string{"boston", "diner", "pizza"}, ids(s.Find(Query{Types: []string{"restaurant", "pizza_restaurant"}})))
+	assert.Equal(t, []string{"pizza"}, ids(s.Find(Query{City: "Halifax", Types: []string{"restaurant"}})))
+
+	near := &geo.Location{Latitude: 44.6488, Longitude: -63.5752}
+	results := s.Find(Query{Near: near, Radius: 1000})
+	assert.Equal(t, []string{"cafe", "pizza"}, ids(results))
+	assert.InDelta(t, 420, *results[0].DistanceMeters, 10)
+	assert.Equal(t, []string{"cafe", "pizza", "diner"}, ids(s.Find(Query{Near: near, Radius: 50000})))
+	assert.Len(t, s.Find(Query{Near: near, Radius: 2000000}), 4) // coarse cells
+	assert.Equal(t, []string{"cafe"}, ids(s.Find(Query{Near: near, Radius: 50000, Limit: 1})))
+
+	assert.Equal(t, []string{"pizza"}, ids(s.Find(Query{OpenAt: time.Now()})))
+}
+
+// Testing upserts keep fields a narrower field mask left out, and the log survives a reopen
+func Test_Upsert_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "places.jsonl")
+	s, err := Open(path)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Upsert(testPlaces()...))
+	assert.NoError(t, s.Upsert(geo.Place{Id: "pizza", Rating: 4.5, Types: []string{"pizza_restaurant"}}, geo.Place{}))
+	assert.NoError(t, s.Delete("boston"))
+	assert.NoError(t, s.Delete("missing"))
+	assert.NoError(t, s.Close())
+	assert.Error(t, s.Upsert(geo.Place{Id: "late"}))
+
+	s, err = Open(path)
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, 3, s.Len())
+	pizza, ok := s.Get("pizza")
+	assert.True(t, ok)
+	assert.Equal(t, 4.5, pizza.Rating)
+	assert.Equal(t, "Pizza Corner", pizza.DisplayName.Text)
+	assert.Equal(t, "Halifax", pizza.City)
+	assert.Equal(t, geo.EncodeGeohash(44.6450, -63.5730, cellPrecision), pizza.Geohash)
+	assert.Empty(t, ids(s.Find(Query{Types: []string{"restaurant"}, City: "Halifax"}))) // types were replaced
+	_, ok = s.Get("boston")
+	assert.False(t, ok)
+}
+
+// Testing the log is compacted once it is mostly superseded lines
+func Test_Compaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "places.jsonl")
+	s, err := Open(path)
+	assert.NoError(t, err)
+	for i := 0; i < 1200; i++ {
+		assert.NoError(t, s.Upsert(geo.Place{Id: "pizza", Rating: float64(i % 5)}))
+	}
+	assert.Less(t, s.lines, 1000)
+	assert.NoError(t, s.Close())
+	s, err = Open(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Len())
+	assert.NoError(t, s.Close())
+}
+
+// Testing text search ranks name matches over type and address matches, biased towards Near
+func Test_Search(t *testing.T) {
+	s, _ := Open("")
+	places := testPlaces()
+	places[2].FormattedAddress = "12 Pizza Lane, Dartmouth"
+	assert.NoError(t, s.Upsert(places...))
+
+	results := s.Search(SearchQuery{Text: "pizza"})
+	assert.Equal(t, []string{"boston", "pizza", "diner"}, ids(results))
+	assert.Greater(t, results[0].Score, results[2].Score)
+	assert.Nil(t, results[0].DistanceMeters)
+
+	near := &geo.Location{Latitude: 44.6488, Longitude: -63.5752}
+	results = s.Search(SearchQuery{Text: "Pizza in Halifax", Near: near, Limit: 2})
+	assert.Equal(t, []string{"pizza", "diner"}, ids(results))
+	assert.NotNil(t, results[0].DistanceMeters)
+	assert.Equal(t, []string{"cafe"}, ids(s.Search(SearchQuery{Text: "harbour café"})))
+	assert.Empty(t, s.Search(SearchQuery{Text: "the"}))
+
+	// The index follows updates and deletes
+	assert.NoError(t, s.Upsert(geo.Place{Id: "cafe", DisplayName: geo.LocalizedText{Text: "Pier Coffee"}}))
+	assert.NoError(t, s.Delete("boston"))
+	assert.Empty(t, s.Search(SearchQuery{Text: "harbour"}))
+	assert.Equal(t, []string{"cafe"}, ids(s.Search(SearchQuery{Text: "coffee"})))
+	assert.Equal(t, []string{"pizza", "diner"}, ids(s.Search(SearchQuery{Text: "pizza"})))
+}
+
+// Testing places past MaxAge are left out of lookups even when nothing has deleted them
+func Test_Expired(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	s, _ := Open("")
+	s.now = func() time.Time { return now }
+	assert.NoError(t, s.Upsert(testPlaces()...))
+	now = now.Add(MaxAge)
+	assert.NoError(t, s.Upsert(geo.Place{Id: "fresh", DisplayName: geo.LocalizedText{Text: "Fresh Pizza"}, Location: geo.Location{Latitude: 44.6455, Longitude: -63.5735}}))
+
+	assert.Equal(t, []string{"fresh"}, ids(s.Find(Query{})))
+	assert.Equal(t, []string{"fresh"}, ids(s.Find(Query{Near: &geo.Location{Latitude: 44.645, Longitude: -63.573}, Radius: 1000})))
+	assert.Equal(t, []string{"fresh"}, ids(s.Search(SearchQuery{Text: "pizza"})))
+	_, ok := s.Get("pizza")
+	assert.False(t, ok)
+	assert.Equal(t, 5, s.Len()) // still stored until the refresher deletes them
+}
@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/geolocate/geo"
+)
+
+// Change is a Place field that differs between two lookups. Old and New hold the field's JSON
+type Change struct {
+	Field string          `json:"field"` // JSON name of the Place field. Eg: businessStatus
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// Index of every Place field by its JSON name, which is also its field mask
+var placeFields = func() map[string]int {
+	fields := map[string]int{}
+	t := reflect.TypeOf(geo.Place{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+// IsPlaceField reports whether name is the JSON name of a Place field
+func IsPlaceField(name string) bool {
+	_, ok := placeFields[name]
+	return ok
+}
+
+// Diff lists the fields, by JSON name, that differ between old and p. Unset and empty values are equal,
+// so a list Google stops returning is not reported until it returns a different one. Opening hours are
+// compared without openNow, nextOpenTime and nextCloseTime, which change whenever a place opens or closes
+func Diff(old, p geo.Place, fields []string) []Change {
+	a, b := reflect.ValueOf(withoutOpenStatus(old)), reflect.ValueOf(withoutOpenStatus(p))
+	var changes []Change
+	for _, name := range fields {
+		i, ok := placeFields[name]
+		if !ok {
+			continue
+		}
+		oldValue, newValue := a.Field(i), b.Field(i)
+		if empty(oldValue) && empty(newValue) {
+			continue
+		}
+		oldJSON, _ := json.Marshal(oldValue.Interface())
+		newJSON, _ := json.Marshal(newValue.Interface())
+		if string(oldJSON) != string(newJSON) {
+			changes = append(changes, Change{Field: name, Old: oldJSON, New: newJSON})
+		}
+	}
+	return changes
+}
+
+// Drop the parts of a place's opening hours that follow the clock rather than its schedule
+func withoutOpenStatus(p geo.Place) geo.Place {
+	p.RegularOpeningHours = scheduleOnly(p.RegularOpeningHours)
+	p.CurrentOpeningHours = scheduleOnly(p.CurrentOpeningHours)
+	if p.RegularSecondaryOpeningHours != nil {
+		secondary := make([]geo.OpeningHours, len(p.RegularSecondaryOpeningHours))
+		for i, hours := range p.RegularSecondaryOpeningHours {
+			secondary[i] = scheduleOnly(hours)
+		}
+		p.RegularSecondaryOpeningHours = secondary
+	}
+	return p
+}
+
+func scheduleOnly(hours geo.OpeningHours) geo.OpeningHours {
+	hours.OpenNow, hours.NextOpenTime, hours.NextCloseTime = false, "", ""
+	return hours
+}
+
+func empty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
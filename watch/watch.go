@@ -0,0 +1,380 @@
+// Package watch tells webhooks when watched places change. Places on a watchlist are looked up on an
+// interval and diffed against the previous lookup, so an ops team hears when a venue closes, changes
+// its hours or its phone number.
+//
+// Deliveries are signed with the watchlist's secret, retried with backoff, and written to a dead
+// letter log when they keep failing.
+package watch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/geolocate/client"
+	"github.com/geolocate/geo"
+	"github.com/geolocate/store"
+)
+
+// Files kept in Options.Dir
+const (
+	stateFile      = "watchlists.json"
+	deadLetterFile = "deadletters.jsonl"
+)
+
+// Fields watched when a watchlist does not name any
+var DefaultFields = []string{"businessStatus", "regularOpeningHours", "nationalPhoneNumber"}
+
+var ErrNotFound = errors.New("watch: watchlist not found")
+
+// Watchlist is a set of places whose changes are posted to a webhook
+type Watchlist struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	PlaceIDs   []string  `json:"placeIds"`
+	Fields     []string  `json:"fields,omitempty"` // Place fields watched, by JSON name. Defaults to DefaultFields
+	WebhookURL string    `json:"webhookUrl"`
+	Secret     string    `json:"secret,omitempty"` // Signs deliveries. Generated when left empty
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Options tune the watcher. Zero values take the defaults
+type Options struct {
+	Interval          time.Duration // Time between checks. Defaults to an hour
+	RequestsPerSecond float64       // Details calls budget. Defaults to 1
+	MaxAttempts       int           // Delivery attempts before an event is dead lettered. Defaults to 5
+	Backoff           time.Duration // Wait before the first retry, doubled for each one after. Defaults to a second
+	Dir               string        // Keeps watchlists, snapshots and dead letters across restarts. Empty keeps them in memory
+	HTTPClient        *http.Client  // Posts webhooks. Defaults to a client with a 10 second timeout
+	Logger            *log.Logger   // Reports failed checks and deliveries. Defaults to the standard logger
+}
+
+// Watcher checks watchlists and delivers their changes. It is safe for concurrent use
+type Watcher struct {
+	fetcher store.DetailsFetcher
+	clock   store.Clock
+	opts    Options
+
+	mu          sync.Mutex
+	lists       map[string]Watchlist
+	snapshots   map[string]geo.Place // Last lookup of each watched place
+	deadLetters []DeadLetter
+}
+
+// What is kept in Options.Dir between restarts
+type state struct {
+	Watchlists []Watchlist          `json:"watchlists"`
+	Snapshots  map[string]geo.Place `json:"snapshots"`
+}
+
+// New creates a watcher, loading its watchlists from opts.Dir when set
+func New(fetcher store.DetailsFetcher, clock store.Clock, opts Options) (*Watcher, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.RequestsPerSecond <= 0 {
+		opts.RequestsPerSecond = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	if clock == nil {
+		clock = store.RealClock
+	}
+	w := &Watcher{fetcher: fetcher, clock: clock, opts: opts, lists: map[string]Watchlist{}, snapshots: map[string]geo.Place{}}
+	if opts.Dir == "" {
+		return w, nil
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(opts.Dir, stateFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		var s state
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		for _, l := range s.Watchlists {
+			w.lists[l.ID] = l
+		}
+		if s.Snapshots != nil {
+			w.snapshots = s.Snapshots
+		}
+	}
+	if w.deadLetters, err = loadDeadLetters(filepath.Join(opts.Dir, deadLetterFile)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Add validates and saves a watchlist, giving it an ID and, when it has none, a secret
+func (w *Watcher) Add(l Watchlist) (Watchlist, error) {
+	if len(l.PlaceIDs) == 0 {
+		return Watchlist{}, errors.New("watch: watchlist has no place IDs")
+	}
+	u, err := url.Parse(l.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Watchlist{}, fmt.Errorf("watch: invalid webhook URL %q", l.WebhookURL)
+	}
+	for _, f := range l.Fields {
+		if !IsPlaceField(f) {
+			return Watchlist{}, fmt.Errorf("watch: unknown place field %q", f)
+		}
+	}
+	if len(l.Fields) == 0 {
+		l.Fields = DefaultFields
+	}
+	l.PlaceIDs = uniqueIDs(l.PlaceIDs)
+	l.ID = randomHex(8)
+	if l.Secret == "" {
+		l.Secret = randomHex(32)
+	}
+	l.CreatedAt = w.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lists[l.ID] = l
+	return l, w.save()
+}
+
+// Get returns the watchlist with id
+func (w *Watcher) Get(id string) (Watchlist, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	l, ok := w.lists[id]
+	return l, ok
+}
+
+// List returns every watchlist, oldest first
+func (w *Watcher) List() []Watchlist {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lists := make([]Watchlist, 0, len(w.lists))
+	for _, l := range w.lists {
+		lists = append(lists, l)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		if !lists[i].CreatedAt.Equal(lists[j].CreatedAt) {
+			return lists[i].CreatedAt.Before(lists[j].CreatedAt)
+		}
+		return lists[i].ID < lists[j].ID
+	})
+	return lists
+}
+
+// Remove deletes a watchlist. Snapshots of places no other watchlist watches are dropped
+func (w *Watcher) Remove(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.lists[id]; !ok {
+		return ErrNotFound
+	}
+	delete(w.lists, id)
+	watched := w.watchedFields()
+	for placeID := range w.snapshots {
+		if _, ok := watched[placeID]; !ok {
+			delete(w.snapshots, placeID)
+		}
+	}
+	return w.save()
+}
+
+// Run checks every Interval until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	for {
+		if err := w.CheckOnce(ctx); err != nil && ctx.Err() == nil {
+			w.opts.Logger.Printf("watch: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.clock.After(w.opts.Interval):
+		}
+	}
+}
+
+// CheckOnce looks up every watched place, pacing calls to the rate budget, and delivers the changes found.
+// The first lookup of a place only records its snapshot
+func (w *Watcher) CheckOnce(ctx context.Context) error {
+	w.mu.Lock()
+	watched := w.watchedFields()
+	w.mu.Unlock()
+	ids := make([]string, 0, len(watched))
+	for id := range watched {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	pause := time.Duration(float64(time.Second) / w.opts.RequestsPerSecond)
+	for i, id := range ids {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-w.clock.After(pause):
+			}
+		}
+		w.check(ctx, id, watched[id])
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.save()
+}
+
+// Look up one place and deliver its changes to every watchlist that watches it
+func (w *Watcher) check(ctx context.Context, id string, fields []string) {
+	masks := []geo.PlaceFieldMask{geo.PlaceFieldMaskPlaceID}
+	for _, f := range fields {
+		masks = append(masks, geo.PlaceFieldMask(f))
+	}
+	place, err := w.fetcher.PlaceDetails(ctx, id, &geo.PlacesHeader{FieldMasks: masks})
+	var httpErr client.HttpError
+	now := w.clock.Now()
+	w.mu.Lock()
+	old, seen := w.snapshots[id]
+	var events []delivery
+	switch {
+	case errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound:
+		// Reported once. With the snapshot gone later lookups are not compared
+		delete(w.snapshots, id)
+		if seen {
+			events = w.events(id, func(Watchlist) Event { return Event{Type: EventPlaceRemoved, PlaceID: id, DetectedAt: now} })
+		}
+	case err != nil:
+		w.opts.Logger.Printf("watch: looking up %s: %s", id, err)
+	default:
+		if place.Id == "" {
+			place.Id = id
+		}
+		if seen {
+			events = w.events(id, func(l Watchlist) Event {
+				changes := Diff(old, place, append([]string{"id"}, l.Fields...))
+				return Event{Type: EventPlaceChanged, PlaceID: id, Changes: changes, Place: &place, DetectedAt: now}
+			})
+		}
+		delete(w.snapshots, id)
+		w.snapshots[place.Id] = place
+		if place.Id != id {
+			w.movePlace(id, place.Id) // Google refreshed the place ID
+		}
+	}
+	w.mu.Unlock()
+	for _, d := range events {
+		w.deliver(ctx, d.list, d.event)
+	}
+}
+
+type delivery struct {
+	list  Watchlist
+	event Event
+}
+
+// Events for every watchlist watching id. Changed events without changes are skipped
+func (w *Watcher) events(id string, event func(Watchlist) Event) []delivery {
+	var deliveries []delivery
+	for _, l := range w.lists {
+		if !slices.Contains(l.PlaceIDs, id) {
+			continue
+		}
+		e := event(l)
+		if e.Type == EventPlaceChanged && len(e.Changes) == 0 {
+			continue
+		}
+		e.ID, e.WatchlistID = randomHex(16), l.ID
+		deliveries = append(deliveries, delivery{list: l, event: e})
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].list.CreatedAt.Before(deliveries[j].list.CreatedAt) })
+	return deliveries
+}
+
+// Watch a place under its new ID in every watchlist
+func (w *Watcher) movePlace(from, to string) {
+	for id, l := range w.lists {
+		if i := slices.Index(l.PlaceIDs, from); i >= 0 {
+			l.PlaceIDs = uniqueIDs(slices.Replace(slices.Clone(l.PlaceIDs), i, i+1, to))
+			w.lists[id] = l
+		}
+	}
+}
+
+// The fields watched for each place, across all watchlists
+func (w *Watcher) watchedFields() map[string][]string {
+	watched := map[string][]string{}
+	for _, l := range w.lists {
+		for _, id := range l.PlaceIDs {
+			for _, f := range l.Fields {
+				if !slices.Contains(watched[id], f) {
+					watched[id] = append(watched[id], f)
+				}
+			}
+		}
+	}
+	return watched
+}
+
+// Write the state file, swapping it in with a rename so a crash leaves the old one intact
+func (w *Watcher) save() error {
+	if w.opts.Dir == "" {
+		return nil
+	}
+	s := state{Watchlists: make([]Watchlist, 0, len(w.lists)), Snapshots: w.snapshots}
+	for _, l := range w.lists {
+		s.Watchlists = append(s.Watchlists, l)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(w.opts.Dir, stateFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(w.opts.Dir, stateFile))
+}
+
+func uniqueIDs(ids []string) []string {
+	var unique []string
+	for _, id := range ids {
+		if id != "" && !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,156 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geolocate/geo"
+	"github.com/geolocate/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Webhook receiver recording the events it verified
+type receiver struct {
+	mu     sync.Mutex
+	secret string
+	events []Event
+	status int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if Verify(r.secret, req.Header.Get(SignatureHeader), body, time.Time{}, 0) != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var e Event
+	json.Unmarshal(body, &e)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+}
+
+// Testing the first check records snapshots and later checks deliver closures, hour changes, moved IDs and removals
+func Test_CheckOnce(t *testing.T) {
+	hook := &receiver{secret: "shh"}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+	clock := testutil.NewClock(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	fetcher := &testutil.Fetcher{Places: map[string]geo.Place{
+		"bar":  {Id: "bar", BusinessStatus: geo.BusinessStatusOperational, PhoneNumber: "902 555 0100"},
+		"cafe": {Id: "cafe", BusinessStatus: geo.BusinessStatusOperational},
+		"pub":  {Id: "pub", BusinessStatus: geo.BusinessStatusOperational},
+	}}
+	w, err := New(fetcher, clock, Options{RequestsPerSecond: 4})
+	assert.NoError(t, err)
+	list, err := w.Add(Watchlist{PlaceIDs: []string{"bar", "cafe", "pub", "bar"}, WebhookURL: srv.URL, Secret: "shh"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "cafe", "pub"}, list.PlaceIDs)
+	assert.Equal(t, DefaultFields, list.Fields)
+
+	ctx := context.Background()
+	assert.NoError(t, w.CheckOnce(ctx))
+	assert.Empty(t, hook.events)
+	assert.Equal(t, []time.Duration{250 * time.Millisecond, 250 * time.Millisecond}, clock.Waits())
+	assert.ElementsMatch(t, []geo.PlaceFieldMask{"id", "businessStatus", "regularOpeningHours", "nationalPhoneNumber"}, fetcher.Masks[0])
+
+	fetcher.Places = map[string]geo.Place{
+		"bar":     {Id: "bar", BusinessStatus: geo.BusinessStatusClosedTemporarily, PhoneNumber: "902 555 0100"},
+		"new-pub": {Id: "new-pub", BusinessStatus: geo.BusinessStatusOperational},
+	}
+	fetcher.Places["pub"] = fetcher.Places["new-pub"]
+	assert.NoError(t, w.CheckOnce(ctx))
+	assert.Len(t, hook.events, 3)
+	byPlace := map[string]Event{}
+	for _, e := range hook.events {
+		assert.Equal(t, list.ID, e.WatchlistID)
+		byPlace[e.PlaceID] = e
+	}
+	assert.Equal(t, EventPlaceChanged, byPlace["bar"].Type)
+	assert.Equal(t, []Change{{Field: "businessStatus", Old: json.RawMessage(`"OPERATIONAL"`), New: json.RawMessage(`"CLOSED_TEMPORARILY"`)}}, byPlace["bar"].Changes)
+	assert.Equal(t, EventPlaceRemoved, byPlace["cafe"].Type)
+	assert.Equal(t, "id", byPlace["pub"].Changes[0].Field)
+
+	list, _ = w.Get(list.ID)
+	assert.Equal(t, []string{"bar", "cafe", "new-pub"}, list.PlaceIDs)
+
+	// Nothing changed, and the removal is not reported twice
+	hook.events = nil
+	assert.NoError(t, w.CheckOnce(ctx))
+	assert.Empty(t, hook.events)
+}
+
+// Testing watchlists and snapshots survive a restart, and removing a watchlist drops its snapshots
+func Test_Watcher_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	clock := testutil.NewClock(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	fetcher := &testutil.Fetcher{Places: map[string]geo.Place{"bar": {Id: "bar", PhoneNumber: "1"}}}
+	w, _ := New(fetcher, clock, Options{Dir: dir})
+	list, err := w.Add(Watchlist{Name: "bars", PlaceIDs: []string{"bar"}, Fields: []string{"nationalPhoneNumber"}, WebhookURL: "https://example.com/hook"})
+	assert.NoError(t, err)
+	assert.Len(t, list.Secret, 64)
+	assert.NoError(t, w.CheckOnce(context.Background()))
+
+	w, err = New(fetcher, clock, Options{Dir: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, []Watchlist{list}, w.List())
+	assert.Equal(t, "1", w.snapshots["bar"].PhoneNumber)
+
+	assert.NoError(t, w.Remove(list.ID))
+	assert.Equal(t, ErrNotFound, w.Remove(list.ID))
+	w, _ = New(fetcher, clock, Options{Dir: dir})
+	assert.Empty(t, w.List())
+	assert.Empty(t, w.snapshots)
+}
+
+// Testing watchlists are validated
+func Test_Add_Invalid(t *testing.T) {
+	w, _ := New(&testutil.Fetcher{}, nil, Options{})
+	for _, l := range []Watchlist{
+		{WebhookURL: "https://example.com"},
+		{PlaceIDs: []string{"a"}, WebhookURL: "ftp://example.com"},
+		{PlaceIDs: []string{"a"}, WebhookURL: "https://example.com", Fields: []string{"phone"}},
+	} {
+		_, err := w.Add(l)
+		assert.Error(t, err)
+	}
+}
+
+// Testing only watched fields are compared, and unset lists equal empty ones
+func Test_Diff(t *testing.T) {
+	old := geo.Place{Id: "a", Rating: 4, PhoneNumber: "1", Types: nil}
+	p := geo.Place{Id: "a", Rating: 3, PhoneNumber: "2", Types: []string{}}
+	assert.Equal(t, []Change{{Field: "nationalPhoneNumber", Old: json.RawMessage(`"1"`), New: json.RawMessage(`"2"`)}},
+		Diff(old, p, []string{"id", "nationalPhoneNumber", "types", "unknown"}))
+	assert.Empty(t, Diff(old, old, []string{"rating", "regularOpeningHours"}))
+}
+
+// Testing a place opening or closing is not reported as a change to its hours
+func Test_Diff_OpenStatus(t *testing.T) {
+	periods := []geo.Period{{Open: geo.Point{Day: 1, Hour: 9}, Close: &geo.Point{Day: 1, Hour: 17}}}
+	closed := geo.Place{Id: "a",
+		RegularOpeningHours:          geo.OpeningHours{Periods: periods, NextOpenTime: "2025-03-03T09:00:00Z"},
+		RegularSecondaryOpeningHours: []geo.OpeningHours{{Periods: periods, SecondaryHoursType: geo.SecondaryHoursTypeDriveThrough}},
+	}
+	open := geo.Place{Id: "a",
+		RegularOpeningHours:          geo.OpeningHours{Periods: periods, OpenNow: true, NextCloseTime: "2025-03-03T17:00:00Z"},
+		RegularSecondaryOpeningHours: []geo.OpeningHours{{Periods: periods, SecondaryHoursType: geo.SecondaryHoursTypeDriveThrough, OpenNow: true}},
+	}
+	fields := []string{"regularOpeningHours", "currentOpeningHours", "regularSecondaryOpeningHours"}
+	assert.Empty(t, Diff(closed, open, fields))
+
+	open.RegularOpeningHours.Periods = []geo.Period{{Open: geo.Point{Day: 1, Hour: 10}, Close: &geo.Point{Day: 1, Hour: 17}}}
+	changes := Diff(closed, open, fields)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "regularOpeningHours", changes[0].Field)
+	assert.True(t, open.RegularSecondaryOpeningHours[0].OpenNow) // the places passed in are left alone
+}
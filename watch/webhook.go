@@ -0,0 +1,205 @@
+package watch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geolocate/geo"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Geolocate-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	EventHeader     = "X-Geolocate-Event"
+	DeliveryHeader  = "X-Geolocate-Delivery" // Event ID, the same across retries so receivers can drop duplicates
+)
+
+// Dead letters kept in memory. The log on disk keeps all of them
+const maxDeadLetters = 100
+
+type EventType string
+
+const (
+	EventPlaceChanged = EventType("place.changed")
+	EventPlaceRemoved = EventType("place.removed") // Google no longer knows the place
+)
+
+// Event is the JSON body of a webhook delivery
+type Event struct {
+	ID          string     `json:"id"`
+	Type        EventType  `json:"type"`
+	WatchlistID string     `json:"watchlistId"`
+	PlaceID     string     `json:"placeId"`
+	Changes     []Change   `json:"changes,omitempty"`
+	Place       *geo.Place `json:"place,omitempty"` // Latest details of the watched fields
+	DetectedAt  time.Time  `json:"detectedAt"`
+}
+
+// DeadLetter is an event that could not be delivered
+type DeadLetter struct {
+	Event    Event     `json:"event"`
+	URL      string    `json:"url"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+var ErrInvalidSignature = errors.New("watch: invalid webhook signature")
+
+// Sign returns the SignatureHeader value for body sent at t
+func Sign(secret string, t time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), signature(secret, t.Unix(), body))
+}
+
+// Verify checks a SignatureHeader value against body. Signatures older than tolerance are rejected so
+// a captured delivery cannot be replayed. A tolerance of 0 accepts any age
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts int64
+	var sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			sig = value
+		}
+	}
+	if ts == 0 || sig == "" || !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && now.Sub(time.Unix(ts, 0)).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func signature(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Post e to the watchlist's webhook, retrying network errors, 429s and 5xxs with exponential backoff.
+// Events that still fail are written to the dead letter log
+func (w *Watcher) deliver(ctx context.Context, list Watchlist, e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		w.deadLetter(DeadLetter{Event: e, URL: list.WebhookURL, Error: err.Error(), FailedAt: w.clock.Now()})
+		return
+	}
+	backoff := w.opts.Backoff
+	attempt := 0
+	for {
+		attempt++
+		retry, err := w.post(ctx, list, e, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= w.opts.MaxAttempts || ctx.Err() != nil {
+			w.deadLetter(DeadLetter{Event: e, URL: list.WebhookURL, Attempts: attempt, Error: err.Error(), FailedAt: w.clock.Now()})
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-w.clock.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// One delivery attempt. Reports whether a failure is worth retrying
+func (w *Watcher) post(ctx context.Context, list Watchlist, e Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, list.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(list.Secret, w.clock.Now(), body))
+	req.Header.Set(EventHeader, string(e.Type))
+	req.Header.Set(DeliveryHeader, e.ID)
+	resp, err := w.opts.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook responded %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+}
+
+func (w *Watcher) deadLetter(d DeadLetter) {
+	w.opts.Logger.Printf("watch: event %s for place %s not delivered to %s: %s", d.Event.ID, d.Event.PlaceID, d.URL, d.Error)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deadLetters = append(w.deadLetters, d)
+	if len(w.deadLetters) > maxDeadLetters {
+		w.deadLetters = w.deadLetters[len(w.deadLetters)-maxDeadLetters:]
+	}
+	if w.opts.Dir == "" {
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(w.opts.Dir, deadLetterFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		w.opts.Logger.Printf("watch: %s", err)
+		return
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(d); err != nil {
+		w.opts.Logger.Printf("watch: %s", err)
+	}
+}
+
+// DeadLetters returns the most recent events that could not be delivered, oldest first
+func (w *Watcher) DeadLetters() []DeadLetter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]DeadLetter{}, w.deadLetters...)
+}
+
+// Read the most recent dead letters back from the log
+func loadDeadLetters(path string) ([]DeadLetter, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var d DeadLetter
+		if json.Unmarshal(scanner.Bytes(), &d) != nil {
+			continue // a line cut short by a crash
+		}
+		letters = append(letters, d)
+		if len(letters) > maxDeadLetters {
+			letters = letters[1:]
+		}
+	}
+	return letters, scanner.Err()
+}
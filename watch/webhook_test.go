@@ -0,0 +1,57 @@
+package watch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/geolocate/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Testing signatures verify against the body, secret and age they were made with
+func Test_Sign(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"1"}`)
+	header := Sign("shh", now, body)
+	assert.NoError(t, Verify("shh", header, body, now.Add(time.Minute), 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, Verify("other", header, body, now, 0))
+	assert.Equal(t, ErrInvalidSignature, Verify("shh", header, []byte(`{"id":"2"}`), now, 0))
+	assert.Equal(t, ErrInvalidSignature, Verify("shh", header, body, now.Add(time.Hour), 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, Verify("shh", "v1=abc", body, now, 0))
+}
+
+// Testing failed deliveries are retried with backoff, then dead lettered and logged to disk
+func Test_Deliver_Retries(t *testing.T) {
+	hook := &receiver{secret: "shh", status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+	clock := testutil.NewClock(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	w, _ := New(&testutil.Fetcher{}, clock, Options{MaxAttempts: 3, Dir: dir})
+	list := Watchlist{ID: "list", WebhookURL: srv.URL, Secret: "shh"}
+	e := Event{ID: "event", Type: EventPlaceRemoved, PlaceID: "bar"}
+
+	w.deliver(context.Background(), list, e)
+	assert.Len(t, hook.events, 3)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, clock.Waits())
+	letters := w.DeadLetters()
+	assert.Len(t, letters, 1)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Equal(t, e, letters[0].Event)
+
+	// Client errors are not retried
+	hook.events, hook.status = nil, http.StatusGone
+	w.deliver(context.Background(), list, e)
+	assert.Len(t, hook.events, 1)
+
+	w, _ = New(&testutil.Fetcher{}, clock, Options{Dir: dir})
+	assert.Len(t, w.DeadLetters(), 2)
+
+	// Delivered events are not dead lettered
+	hook.status = 0
+	w.deliver(context.Background(), list, e)
+	assert.Len(t, w.DeadLetters(), 2)
+}